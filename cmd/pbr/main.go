@@ -46,7 +46,14 @@ func run(o *Options) error {
 		m := materials[strings.ToLower(o.Material)]
 		mesh.SetMaterial(m)
 	}
-	bounds, surfaces := mesh.Bounds()
+	if o.Subdivide > 0 {
+		mesh.Subdivide(o.Subdivide)
+	}
+	if o.Displace > 0 {
+		mesh.Displace(o.Displace)
+	}
+	m := mesh.Indexed(o.Compact)
+	bounds, surfaces := m.Bounds(), []render.Surface{m}
 	camera := camera.NewSLR()
 	environment := render.Environment(env.NewGradient(rgb.Black, *o.Ambient, 3))
 
@@ -57,7 +64,7 @@ func run(o *Options) error {
 	camera.Focus = o.Focus
 
 	if o.Verbose || o.Info {
-		printInfo(bounds, len(surfaces), camera)
+		printInfo(bounds, m.Len(), camera)
 		if o.Info {
 			return nil
 		}
@@ -71,10 +78,10 @@ func run(o *Options) error {
 	}
 
 	if o.Floor > 0 {
-		floor := surface.UnitCube(material.Plastic(o.FloorColor.X, o.FloorColor.Y, o.FloorColor.Z, o.FloorRough))
+		floor := surface.UnitQuad(material.Plastic(o.FloorColor.X, o.FloorColor.Y, o.FloorColor.Z, o.FloorRough))
 		dims := bounds.Max.Minus(bounds.Min).Scaled(o.Floor)
-		floor.Shift(geom.Vec{bounds.Center.X, bounds.Min.Y - dims.Y*0.5, bounds.Center.Z})
-		floor.Scale(geom.Vec{dims.X, dims.Y, dims.Z})
+		floor.Shift(geom.Vec{bounds.Center.X, bounds.Min.Y, bounds.Center.Z})
+		floor.Scale(geom.Vec{dims.X, 1, dims.Z})
 		surfaces = append(surfaces, floor)
 	}
 
@@ -84,9 +91,18 @@ func run(o *Options) error {
 		surfaces = append(surfaces, sun)
 	}
 
-	tree := surface.NewTree(surfaces...)
+	tree := surface.NewBVH(surfaces...)
 	scene := render.NewScene(camera, tree, environment)
+	if o.LightTree {
+		scene.Lights = render.NewLightTree(tree.Lights()...)
+	}
+	integrator, err := render.NewIntegrator(o.Integrator, o.Bounce, !o.Indirect)
+	if err != nil {
+		return err
+	}
+	scene.Integrator = integrator
 
-	fmt.Println("Surfaces:", len(surfaces))
-	return render.Iterative(scene, o.Out, o.Width, o.Height, o.Bounce, !o.Indirect)
+	fmt.Println("Triangles:", m.Len())
+	opts := render.Options{Frames: o.Frames, Time: o.Time, Error: o.Converge, Seed: o.Seed}
+	return render.Iterative(scene, o.Out, o.Width, o.Height, o.Bounce, !o.Indirect, opts)
 }
@@ -12,12 +12,17 @@ import (
 // Options configures rendering behavior.
 // TODO: add "watermark"
 type Options struct {
-	Scene    string  `arg:"positional,required" help:"input scene .obj"`
-	Verbose  bool    `arg:"-v" help:"verbose output with scene information"`
-	Info     bool    `help:"output scene information and exit"`
-	Frames   float64 `arg:"-f" help:"number of frames at which to exit"`
-	Time     float64 `arg:"-t" help:"time to run before exiting (seconds)"`
-	Material string  `help:"override material (glass, gold, mirror, plastic)"`
+	Scene     string  `arg:"positional,required" help:"input scene .obj"`
+	Verbose   bool    `arg:"-v" help:"verbose output with scene information"`
+	Info      bool    `help:"output scene information and exit"`
+	Frames    float64 `arg:"-f" help:"number of frames at which to exit"`
+	Time      float64 `arg:"-t" help:"time to run before exiting (seconds)"`
+	Converge  float64 `arg:"-c" help:"relative pixel error at which to exit (enables adaptive sampling)"`
+	Seed      int64   `help:"random seed for reproducible renders (0 for a random seed)"`
+	Material  string  `help:"override material (glass, gold, mirror, plastic)"`
+	Compact   bool    `help:"store mesh vertices at single precision to save memory"`
+	Subdivide int     `help:"smooth the mesh by subdividing its faces this many times over"`
+	Displace  int     `help:"split mesh faces this many times over and move them by their displacement maps"`
 
 	Width  int       `arg:"-w" help:"rendering width in pixels"`
 	Height int       `arg:"-h" help:"rendering height in pixels"`
@@ -33,11 +38,13 @@ type Options struct {
 	To    *geom.Vec `help:"camera look point"`
 	Focus float64   `help:"camera focus ratio"`
 
-	Lens     float64 `help:"camera focal length in mm"`
-	FStop    float64 `help:"camera f-stop"`
-	Expose   float64 `help:"exposure multiplier"`
-	Bounce   int     `arg:"-b" help:"number of indirect light bounces"`
-	Indirect bool    `help:"indirect lighting only (no direct shadow rays)"`
+	Lens       float64 `help:"camera focal length in mm"`
+	FStop      float64 `help:"camera f-stop"`
+	Expose     float64 `help:"exposure multiplier"`
+	Bounce     int     `arg:"-b" help:"number of indirect light bounces"`
+	Indirect   bool    `help:"indirect lighting only (no direct shadow rays)"`
+	LightTree  bool    `help:"choose lights with a spatial light tree (for scenes with many emitters)"`
+	Integrator string  `arg:"-i" help:"rendering algorithm: path, bidirectional, direct, ao, normals, albedo, or depth"`
 
 	Ambient    *rgb.Energy `help:"the ambient light color"`
 	Env        string      `arg:"-e" help:"environment as a panoramic hdr radiosity map (.hdr file)"`
@@ -0,0 +1,57 @@
+package bsdf
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+)
+
+const (
+	albedoSteps      = 32 // of the cosine and roughness in an albedo table
+	albedoQuadrature = 32 // steps in each angle when integrating over the hemisphere
+)
+
+// albedo is a table of the fraction of light that a lobe reflects, in all, towards each angle,
+// from surfaces of each roughness from 0 to 1.
+// It's integrated numerically the first time it's used.
+type albedo struct {
+	lobe  func(wi, wo geom.Dir, roughness float64) float64 // including the cosine factor
+	once  sync.Once
+	table [albedoSteps][albedoSteps]float64
+}
+
+// at returns the albedo towards a direction with cosine cosO from the normal, interpolated from the table.
+func (a *albedo) at(cosO, roughness float64) float64 {
+	a.once.Do(a.fill)
+	x := math.Max(0, math.Min(1, cosO)) * (albedoSteps - 1)
+	y := math.Max(0, math.Min(1, roughness)) * (albedoSteps - 1)
+	i, j := math.Min(math.Floor(x), albedoSteps-2), math.Min(math.Floor(y), albedoSteps-2)
+	u, v := x-i, y-j
+	t := &a.table
+	r0 := t[int(i)][int(j)]*(1-v) + t[int(i)][int(j)+1]*v
+	r1 := t[int(i)+1][int(j)]*(1-v) + t[int(i)+1][int(j)+1]*v
+	return r0*(1-u) + r1*u
+}
+
+// fill integrates the lobe over the hemisphere for each entry in the table.
+// Light leaves in the XY plane, so only half the hemisphere is integrated.
+func (a *albedo) fill() {
+	dTheta, dPhi := math.Pi/2/albedoQuadrature, math.Pi/albedoQuadrature
+	for i := range a.table {
+		cos := float64(i) / (albedoSteps - 1)
+		wo := geom.Dir{math.Sqrt(1 - cos*cos), cos, 0}
+		for j := range a.table[i] {
+			roughness := float64(j) / (albedoSteps - 1)
+			sum := 0.0
+			for k := 0; k < albedoQuadrature; k++ {
+				theta := (float64(k) + 0.5) * dTheta
+				for l := 0; l < albedoQuadrature; l++ {
+					wi, _ := geom.SphericalDirection(theta, (float64(l)+0.5)*dPhi)
+					sum += a.lobe(wi, wo, roughness) * math.Sin(theta)
+				}
+			}
+			a.table[i][j] = 2 * sum * dTheta * dPhi
+		}
+	}
+}
@@ -2,6 +2,7 @@ package bsdf
 
 import (
 	"math"
+	"math/rand"
 
 	"github.com/hunterloftis/pbr2/pkg/geom"
 )
@@ -14,22 +15,46 @@ func fresnelSchlick(cosTheta, f0 float64) float64 {
 	return math.Max(0, math.Min(1, f0+(1-f0)*x))
 }
 
+// minAlpha keeps perfectly smooth microfacet surfaces from dividing by zero.
+const minAlpha = 0.001
+
+// alpha returns the width of the GGX distribution for a roughness.
+func alpha(roughness float64) float64 {
+	return math.Max(roughness, minAlpha)
+}
+
 // GGX Normal Distribution Function
 // http://graphicrants.blogspot.com/2013/08/specular-brdf-reference.html
 func ggx(in, out, normal geom.Dir, roughness float64) float64 {
-	wm := in.Half(out)
-	a := roughness
+	return ggxD(normal.Dot(in.Half(out)), roughness)
+}
+
+// ggxD returns the density of GGX microfacets whose normals are at cosTheta from the surface normal.
+func ggxD(cosTheta, roughness float64) float64 {
+	a := alpha(roughness)
 	a2 := a * a
-	cosTheta := normal.Dot(wm)
 	exp := (a2-1)*cosTheta*cosTheta + 1
 	return a2 / (math.Pi * exp * exp)
 }
 
+// sampleGGX returns a microfacet normal, above the surface, with a density of ggxD(cosTheta) * cosTheta.
+// https://schuttejoe.github.io/post/ggximportancesamplingpart1/
+func sampleGGX(roughness float64, rnd *rand.Rand) geom.Dir {
+	r0 := rnd.Float64()
+	r1 := rnd.Float64()
+	a := alpha(roughness)
+	a2 := a * a
+	theta := math.Acos(math.Sqrt((1 - r0) / ((a2-1)*r0 + 1)))
+	phi := 2 * math.Pi * r1
+	wm, _ := geom.SphericalDirection(theta, phi)
+	return wm
+}
+
 // Smith geometric shadowing for a GGX distribution
 // http://graphicrants.blogspot.com/2013/08/specular-brdf-reference.html
 func smithGGX(out, normal geom.Dir, roughness float64) float64 {
-	a := roughness * roughness
+	a := alpha(roughness)
 	a2 := a * a
-	nv := normal.Dot(out)
+	nv := math.Abs(normal.Dot(out))
 	return (2 * nv) / (nv + math.Sqrt(a2+(1-a2)*nv*nv))
 }
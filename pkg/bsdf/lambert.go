@@ -19,10 +19,10 @@ func (l Lambert) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
 }
 
 func (l Lambert) PDF(wi, wo geom.Dir) float64 {
-	return wi.Dot(geom.Up) * math.Pi
+	return math.Max(0, wi.Dot(geom.Up)) / math.Pi
 }
 
 func (l Lambert) Eval(wi, wo geom.Dir) rgb.Energy {
-	cos := wi.Dot(geom.Up)
-	return l.Color.Scaled(cos * l.Multiplier)
+	cos := math.Max(0, wi.Dot(geom.Up))
+	return l.Color.Scaled(cos / math.Pi * l.Multiplier)
 }
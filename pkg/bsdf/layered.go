@@ -0,0 +1,251 @@
+package bsdf
+
+import (
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Layered is a dielectric coat over a diffuse base, part of which may be bare metal,
+// or part of which may be a dielectric that transmits light, like glass.
+// The coat reflects light by its Fresnel reflectance and lets the rest through to the base,
+// so glancing light reflects more and reaches the base less, and no more light leaves than arrives.
+// The dielectric base may have a sheen, like the fuzz of cloth, over the coat, which the coat only receives light through,
+// and its diffuse base may scatter light under its surface, like skin or marble.
+// A clearcoat, like the lacquer on car paint, is a second glossy layer over all of that,
+// which reflects some light by its own Fresnel reflectance, at its own roughness, and lets the rest through.
+// Lobes are sampled in proportion to an estimate of how much light each reflects.
+// Light that a smooth dielectric transmits can't be found by sampling lights, so only Sample chooses it,
+// with the chance it returns as its density; Eval and PDF describe all the other lobes together,
+// which Lit says can still be lit by sampling lights when Sample chooses to transmit.
+// https://blog.selfshadow.com/publications/s2012-shading-course/burley/s2012_pbs_disney_brdf_notes_v3.pdf
+type Layered struct {
+	Color     rgb.Energy // of the base, and of the metal
+	Metalness float64    // fraction of the surface that's bare metal
+	Specular  float64    // reflectance of the coat at normal incidence
+	Roughness float64
+	Transmit  float64 // fraction of the surface that's a transmissive dielectric rather than a coated diffuse base
+
+	Sheen      float64 // strength of the sheen, from 0 (none) to 1
+	Subsurface float64 // fraction of the diffuse base that scatters light under its surface rather than like Lambert
+
+	Clearcoat          float64 // strength of the clearcoat, from 0 (none) to 1
+	ClearcoatRoughness float64
+}
+
+// clearcoatSpecular is the reflectance of the clearcoat at normal incidence, for an index of refraction of 1.5.
+const clearcoatSpecular = 0.04
+
+const (
+	metalLobe = iota
+	coatLobe
+	diffuseLobe
+	sheenLobe
+	transmitLobe
+	clearcoatLobe
+	lobes
+)
+
+func (l Layered) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
+	p := l.chances(wo)
+	delta := l.delta(p)
+	r := rnd.Float64()
+	if r < delta {
+		wi, pdf, _ := l.transmit().Sample(wo, rnd)
+		return wi, delta * pdf, false
+	}
+	r -= delta
+	var wi geom.Dir
+	var pdf float64
+	switch {
+	case r < p[metalLobe]:
+		wi, pdf, _ = l.metal().Sample(wo, rnd)
+	case r < p[metalLobe]+p[coatLobe]:
+		wi, pdf, _ = l.coat().Sample(wo, rnd)
+	case r < p[metalLobe]+p[coatLobe]+p[diffuseLobe]:
+		wi, pdf, _ = l.diffuse().Sample(wo, rnd)
+	case r < p[metalLobe]+p[coatLobe]+p[diffuseLobe]+p[sheenLobe]:
+		wi, pdf, _ = l.sheen().Sample(wo, rnd)
+	case r < p[metalLobe]+p[coatLobe]+p[diffuseLobe]+p[sheenLobe]+p[clearcoatLobe]:
+		wi, pdf, _ = l.clearcoat().Sample(wo, rnd)
+	case p[transmitLobe] > delta:
+		wi, pdf, _ = l.transmit().Sample(wo, rnd)
+	}
+	if pdf <= 0 {
+		return wo.Inv(), 0, false // reflects nothing
+	}
+	return wi, l.PDF(wi, wo), true
+}
+
+func (l Layered) Lit(wo geom.Dir) bool {
+	return l.delta(l.chances(wo)) < 1
+}
+
+func (l Layered) PDF(wi, wo geom.Dir) float64 {
+	p := l.chances(wo)
+	delta := l.delta(p)
+	if delta > 0 && l.transmitted(wi, wo) {
+		return delta * l.transmit().PDF(wi, wo)
+	}
+	pdf := 0.0
+	if p[metalLobe] > 0 {
+		pdf += p[metalLobe] * l.metal().PDF(wi, wo)
+	}
+	if p[coatLobe] > 0 {
+		pdf += p[coatLobe] * l.coat().PDF(wi, wo)
+	}
+	if p[diffuseLobe] > 0 {
+		pdf += p[diffuseLobe] * l.diffuse().PDF(wi, wo)
+	}
+	if p[sheenLobe] > 0 {
+		pdf += p[sheenLobe] * l.sheen().PDF(wi, wo)
+	}
+	if p[transmitLobe] > delta {
+		pdf += p[transmitLobe] * l.transmit().PDF(wi, wo)
+	}
+	if p[clearcoatLobe] > 0 {
+		pdf += p[clearcoatLobe] * l.clearcoat().PDF(wi, wo)
+	}
+	return pdf
+}
+
+func (l Layered) Eval(wi, wo geom.Dir) rgb.Energy {
+	w := l.weights(wo)
+	delta := l.delta(l.chances(wo))
+	out := l.through(wi) // the clearcoat's transmittance on the way out
+	if delta > 0 && l.transmitted(wi, wo) {
+		return l.transmit().Eval(wi, wo).Scaled(w[transmitLobe] * out)
+	}
+	e := rgb.Black
+	if w[metalLobe] > 0 {
+		e = e.Plus(l.metal().Eval(wi, wo).Scaled(w[metalLobe]))
+	}
+	fuzz := l.fuzz(wi) // the sheen's transmittance on the way out
+	if w[coatLobe] > 0 {
+		e = e.Plus(l.coat().Eval(wi, wo).Scaled(w[coatLobe] * fuzz))
+	}
+	if w[diffuseLobe] > 0 {
+		through := 1 - fresnelSchlick(wi.Y, l.Specular) // the coat's transmittance on the way out
+		e = e.Plus(l.base(wi, wo).Scaled(w[diffuseLobe] * through * fuzz))
+	}
+	if w[sheenLobe] > 0 {
+		e = e.Plus(l.sheen().Eval(wi, wo).Scaled(w[sheenLobe]))
+	}
+	if w[transmitLobe] > 0 && delta == 0 {
+		e = e.Plus(l.transmit().Eval(wi, wo).Scaled(w[transmitLobe]))
+	}
+	e = e.Scaled(out)
+	if w[clearcoatLobe] > 0 {
+		e = e.Plus(l.clearcoat().Eval(wi, wo).Scaled(w[clearcoatLobe]))
+	}
+	return e
+}
+
+// weights returns how much each lobe contributes when light leaves towards wo.
+// The base only receives the light that the coat doesn't reflect,
+// the coat only receives the light that the sheen doesn't reflect,
+// and everything under the clearcoat only receives the light that the clearcoat doesn't reflect.
+func (l Layered) weights(wo geom.Dir) (w [lobes]float64) {
+	f := fresnelSchlick(wo.Y, l.Specular)
+	in := l.through(wo)
+	dielectric := 1 - l.Metalness
+	w[metalLobe] = in * l.Metalness
+	w[sheenLobe] = in * dielectric * (1 - l.Transmit) * l.Sheen
+	w[coatLobe] = in * dielectric * (1 - l.Transmit) * l.fuzz(wo)
+	w[diffuseLobe] = w[coatLobe] * (1 - f)
+	w[transmitLobe] = in * dielectric * l.Transmit // which reflects light itself
+	w[clearcoatLobe] = l.Clearcoat
+	return w
+}
+
+// fuzz returns the fraction of light that passes through the sheen in direction w.
+func (l Layered) fuzz(w geom.Dir) float64 {
+	if l.Sheen == 0 || w.Y <= 0 {
+		return 1
+	}
+	return 1 - l.Sheen*sheenAlbedo.at(w.Y, 0)
+}
+
+// through returns the fraction of light that passes through the clearcoat in direction w.
+func (l Layered) through(w geom.Dir) float64 {
+	if l.Clearcoat == 0 || w.Y <= 0 {
+		return 1
+	}
+	return 1 - l.Clearcoat*fresnelSchlick(w.Y, clearcoatSpecular)
+}
+
+// chances returns the chance of sampling each lobe when light leaves towards wo,
+// in proportion to an estimate of how much light each lobe reflects.
+func (l Layered) chances(wo geom.Dir) (p [lobes]float64) {
+	w := l.weights(wo)
+	f := fresnelSchlick(wo.Y, l.Specular)
+	p[metalLobe] = w[metalLobe] * l.Color.Mean()
+	p[coatLobe] = w[coatLobe] * f
+	p[diffuseLobe] = w[diffuseLobe] * l.Color.Mean()
+	if w[sheenLobe] > 0 {
+		p[sheenLobe] = w[sheenLobe] * sheenAlbedo.at(wo.Y, 0)
+	}
+	p[transmitLobe] = w[transmitLobe]
+	p[clearcoatLobe] = w[clearcoatLobe] * fresnelSchlick(wo.Y, clearcoatSpecular)
+	sum := p[metalLobe] + p[coatLobe] + p[diffuseLobe] + p[sheenLobe] + p[transmitLobe] + p[clearcoatLobe]
+	if sum <= 0 {
+		return [lobes]float64{}
+	}
+	for i := range p {
+		p[i] /= sum
+	}
+	return p
+}
+
+// delta returns the chance of Sample choosing to transmit through a smooth dielectric, given chances p.
+func (l Layered) delta(p [lobes]float64) float64 {
+	if l.Roughness == 0 {
+		return p[transmitLobe]
+	}
+	return 0
+}
+
+// transmitted returns whether light from wo could be reflected or refracted towards wi by a smooth dielectric.
+func (l Layered) transmitted(wi, wo geom.Dir) bool {
+	return !l.transmit().Eval(wi, wo).Zero()
+}
+
+func (l Layered) metal() Microfacet {
+	return Microfacet{Specular: l.Color, Roughness: l.Roughness, Multiplier: 1}
+}
+
+func (l Layered) coat() Microfacet {
+	return Microfacet{Specular: rgb.Energy{l.Specular, l.Specular, l.Specular}, Roughness: l.Roughness, Multiplier: 1}
+}
+
+func (l Layered) diffuse() Lambert {
+	return Lambert{Color: l.Color, Multiplier: 1}
+}
+
+// base returns the light that the diffuse base reflects from wi to wo, part of which scatters under its surface.
+func (l Layered) base(wi, wo geom.Dir) rgb.Energy {
+	e := l.diffuse().Eval(wi, wo)
+	if l.Subsurface == 0 {
+		return e
+	}
+	return e.Lerp(l.subsurface().Eval(wi, wo), l.Subsurface)
+}
+
+func (l Layered) subsurface() Subsurface {
+	return Subsurface{Color: l.Color, Roughness: l.Roughness, Multiplier: 1}
+}
+
+// sheen is tinted halfway towards the color of the base.
+func (l Layered) sheen() Sheen {
+	return Sheen{Color: l.Color.Lerp(rgb.White, 0.5), Multiplier: 1}
+}
+
+func (l Layered) clearcoat() Microfacet {
+	s := clearcoatSpecular
+	return Microfacet{Specular: rgb.Energy{s, s, s}, Roughness: l.ClearcoatRoughness, Multiplier: 1}
+}
+
+func (l Layered) transmit() Transmit {
+	return Transmit{Specular: l.Specular, Roughness: l.Roughness, Multiplier: 1}
+}
@@ -0,0 +1,124 @@
+package bsdf_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/bsdf"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// TestLayeredFurnace lights Layered BSDFs from every direction with white light (a white furnace)
+// and checks that they reflect no more light than arrives, that white ones lose little of it,
+// and that their samples agree with an estimate from directions chosen uniformly.
+func TestLayeredFurnace(t *testing.T) {
+	white := rgb.Energy{1, 1, 1}
+	tests := []struct {
+		name string
+		bsdf bsdf.Layered
+		min  float64 // least light to reflect, when lit from near the normal
+	}{
+		{"smooth plastic", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.05}, 0.9},
+		{"rough plastic", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.8}, 0.85},
+		{"shiny plastic", bsdf.Layered{Color: white, Specular: 0.2, Roughness: 0.3}, 0.75},
+		{"mirror", bsdf.Layered{Color: white, Metalness: 1}, 0.95},
+		{"metal", bsdf.Layered{Color: white, Metalness: 1, Roughness: 0.3}, 0.8},
+		{"rough metal", bsdf.Layered{Color: white, Metalness: 1, Roughness: 0.8}, 0.4}, // without multiple scattering between microfacets
+		{"half metal", bsdf.Layered{Color: white, Metalness: 0.5, Specular: 0.04, Roughness: 0.4}, 0.8},
+		{"cloth", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.8, Sheen: 1}, 0.85},
+		{"marble", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.3, Subsurface: 1}, 0.85},
+		{"clearcoat", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.5, Clearcoat: 1, ClearcoatRoughness: 0.2}, 0.8},
+		{"car paint", bsdf.Layered{Color: white, Metalness: 0.5, Specular: 0.04, Roughness: 0.3, Clearcoat: 1, ClearcoatRoughness: 0.02}, 0.75},
+		{"glass", bsdf.Layered{Color: white, Specular: 0.04, Transmit: 1}, 0.95},
+		{"frosted glass", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.2, Transmit: 1}, 0.95},
+		{"rough glass", bsdf.Layered{Color: white, Specular: 0.04, Roughness: 0.6, Transmit: 1}, 0.85},
+	}
+	const n = 50000
+	for _, test := range tests {
+		rnd := rand.New(rand.NewSource(1))
+		// light is seen from inside dielectrics too, where some of it is totally internally reflected
+		cosines := []float64{1, 0.7, 0.3, 0.1}
+		if test.bsdf.Transmit > 0 {
+			cosines = append(cosines, -1, -0.7, -0.3, -0.1)
+		}
+		for _, cos := range cosines {
+			wo := geom.Dir{math.Sqrt(1 - cos*cos), cos, 0}
+			sampled, uniform := 0.0, 0.0
+			for i := 0; i < n; i++ {
+				if wi, pdf, _ := test.bsdf.Sample(wo, rnd); pdf > 0 {
+					sampled += test.bsdf.Eval(wi, wo).Mean() / pdf
+				}
+			}
+			sampled /= n
+			if test.bsdf.Transmit > 0 {
+				for i := 0; i < 4*n; i++ { // over the whole sphere, which is noisier
+					wi := geom.RandDirection(rnd)
+					uniform += test.bsdf.Eval(wi, wo).Mean() * 4 * math.Pi
+				}
+				uniform /= 4 * n
+			} else {
+				for i := 0; i < n; i++ {
+					wi := geom.Up.RandHemi(rnd)
+					uniform += test.bsdf.Eval(wi, wo).Mean() * 2 * math.Pi
+				}
+				uniform /= n
+			}
+			if sampled > 1.01 {
+				t.Errorf("%v at cos %v: reflects %.3f of the light", test.name, cos, sampled)
+			}
+			if cos >= 0.7 && sampled < test.min {
+				t.Errorf("%v at cos %v: reflects %.3f of the light, expected at least %v", test.name, cos, sampled, test.min)
+			}
+			// uniform directions can't find perfect reflections and refractions, and rarely find sharp ones
+			rough, tolerance := test.bsdf.Roughness >= 0.2 && (test.bsdf.Clearcoat == 0 || test.bsdf.ClearcoatRoughness >= 0.2), 0.02
+			if test.bsdf.Transmit > 0 {
+				rough, tolerance = test.bsdf.Roughness >= 0.5, 0.05
+			}
+			if rough && math.Abs(sampled-uniform) > tolerance {
+				t.Errorf("%v at cos %v: samples reflect %.3f, uniform directions %.3f", test.name, cos, sampled, uniform)
+			}
+		}
+	}
+}
+
+// TestLayeredLights checks that light found by sampling a small light agrees with light found by sampling the BSDF
+// for Layered BSDFs that sometimes transmit through a smooth dielectric, which only Sample can choose.
+func TestLayeredLights(t *testing.T) {
+	white := rgb.Energy{1, 1, 1}
+	tests := []struct {
+		name string
+		bsdf bsdf.Layered
+	}{
+		{"half glass", bsdf.Layered{Color: white, Specular: 0.04, Transmit: 0.5}},
+		{"clearcoat over glass", bsdf.Layered{Color: white, Specular: 0.04, Transmit: 1, Clearcoat: 1, ClearcoatRoughness: 0.5}},
+	}
+	// away from where the surface reflects or refracts the light perfectly
+	light := geom.NewBounds(geom.Vec{-0.5, 4.25, 1.05}, geom.Vec{0.5, 5.25, 2.05})
+	const n = 20000
+	for _, test := range tests {
+		rnd := rand.New(rand.NewSource(1))
+		for _, cos := range []float64{1, 0.7, 0.3} {
+			wo := geom.Dir{math.Sqrt(1 - cos*cos), cos, 0}
+			lit, sampled := 0.0, 0.0
+			for i := 0; i < n; i++ {
+				wi, pdf := light.SampleCone(geom.Vec{}, rnd)
+				lit += test.bsdf.Eval(wi, wo).Mean() / pdf
+			}
+			lit /= n
+			for i := 0; i < 20*n; i++ {
+				if wi, pdf, _ := test.bsdf.Sample(wo, rnd); pdf > 0 && light.ConePDF(geom.Vec{}, wi) > 0 {
+					sampled += test.bsdf.Eval(wi, wo).Mean() / pdf
+				}
+			}
+			sampled /= 20 * n
+			if lit == 0 {
+				t.Errorf("%v at cos %v: reflects no light", test.name, cos)
+			}
+			if math.Abs(lit-sampled) > 0.05*lit {
+				t.Errorf("%v at cos %v: reflects %.4f from sampling the light, %.4f from sampling the BSDF", test.name, cos, lit, sampled)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package bsdf
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Sheen is the soft glow of cloth, from light that glances off its fibers.
+// It reflects most when light arrives and leaves at grazing angles, so it brightens the edges of fabric.
+// https://blog.selfshadow.com/publications/s2012-shading-course/burley/s2012_pbs_disney_brdf_notes_v3.pdf
+type Sheen struct {
+	Color      rgb.Energy
+	Multiplier float64
+}
+
+func (s Sheen) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
+	wi, _ := geom.Up.RandHemiCos(rnd)
+	return wi, s.PDF(wi, wo), wo.Dot(geom.Up) > 0
+}
+
+func (s Sheen) PDF(wi, wo geom.Dir) float64 {
+	return math.Max(0, wi.Dot(geom.Up)) / math.Pi
+}
+
+func (s Sheen) Eval(wi, wo geom.Dir) rgb.Energy {
+	if wi.Y <= 0 || wo.Y <= 0 {
+		return rgb.Black
+	}
+	return s.Color.Scaled(sheen(wi, wo, 0) * s.Multiplier)
+}
+
+// sheen returns the light that the sheen reflects from wi to wo, including the cosine factor.
+func sheen(wi, wo geom.Dir, roughness float64) float64 {
+	return math.Pow(1-wi.Dot(wi.Half(wo)), 5) * wi.Y
+}
+
+// sheenAlbedo is the fraction of light that the sheen reflects.
+var sheenAlbedo = &albedo{lobe: sheen}
@@ -0,0 +1,50 @@
+package bsdf
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Subsurface is a diffuse surface that light scatters through just under, like skin, marble, or milk.
+// Light that arrives at a glancing angle scatters out again before it's absorbed,
+// so it looks flatter than a Lambertian surface and brighter where light grazes it,
+// after the Hanrahan-Krueger approximation in Disney's BRDF.
+// It reflects Color from every angle, like Lambert; only the directions that the light comes from change.
+// https://blog.selfshadow.com/publications/s2012-shading-course/burley/s2012_pbs_disney_brdf_notes_v3.pdf
+type Subsurface struct {
+	Color      rgb.Energy
+	Roughness  float64
+	Multiplier float64
+}
+
+func (s Subsurface) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
+	wi, _ := geom.Up.RandHemiCos(rnd)
+	return wi, s.PDF(wi, wo), wo.Dot(geom.Up) > 0
+}
+
+func (s Subsurface) PDF(wi, wo geom.Dir) float64 {
+	return math.Max(0, wi.Dot(geom.Up)) / math.Pi
+}
+
+func (s Subsurface) Eval(wi, wo geom.Dir) rgb.Energy {
+	if wi.Y <= 0 || wo.Y <= 0 {
+		return rgb.Black
+	}
+	r := subsurface(wi, wo, s.Roughness) / subsurfaceAlbedo.at(wo.Y, s.Roughness)
+	return s.Color.Scaled(r * s.Multiplier)
+}
+
+// subsurface returns the light that Disney's subsurface lobe reflects from wi to wo, including the cosine factor.
+func subsurface(wi, wo geom.Dir, roughness float64) float64 {
+	cosD := wi.Dot(wi.Half(wo))
+	f90 := cosD * cosD * roughness
+	fi, fo := math.Pow(1-wi.Y, 5), math.Pow(1-wo.Y, 5)
+	f := (1 + (f90-1)*fi) * (1 + (f90-1)*fo)
+	return 1.25 / math.Pi * (f*(1/(wi.Y+wo.Y)-0.5) + 0.5) * wi.Y
+}
+
+// subsurfaceAlbedo is the fraction of light that Disney's subsurface lobe reflects, which can be more than all of it.
+var subsurfaceAlbedo = &albedo{lobe: subsurface}
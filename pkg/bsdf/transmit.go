@@ -8,55 +8,155 @@ import (
 	"github.com/hunterloftis/pbr2/pkg/rgb"
 )
 
-// Simple, perfect refraction with no roughness
+// Transmit is the boundary of a dielectric, like glass or water, seen from either side.
+// It reflects and refracts light in the proportions given by the Fresnel equations.
+// Rough boundaries are made of GGX microfacets, and smooth ones (Roughness 0) reflect and refract perfectly.
+// Radiance isn't scaled by the squared ratio of the indices of refraction as light crosses the boundary,
+// which cancels out for light that passes into and back out of an object.
+// https://www.cs.cornell.edu/~srm/publications/EGSR07-btdf.pdf
 type Transmit struct {
-	Specular   float64
+	Specular   float64 // reflectance at normal incidence, from which the index of refraction is found
 	Roughness  float64
 	Multiplier float64
 }
 
 func (t Transmit) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
-	ior := fresnelToRefractiveIndex(t.Specular)
-	return refract(wo.Inv(), geom.Up, ior), 1, false
+	eta := t.eta(wo)
+	if t.Roughness == 0 {
+		n := facing(geom.Up, wo)
+		f := fresnelDielectric(wo.Dot(n), eta)
+		if rnd.Float64() < f {
+			return wo.Reflect2(n), f, false
+		}
+		wi, _ := refracted(wo, n, eta)
+		return wi, 1 - f, false
+	}
+	wm := facing(sampleGGX(t.Roughness, rnd), facing(geom.Up, wo))
+	if wo.Dot(wm) <= 0 {
+		return wo.Inv(), 0, true // a microfacet facing away
+	}
+	wi, reflects := wo.Reflect2(wm), true
+	if refracts, ok := refracted(wo, wm, eta); ok && rnd.Float64() >= fresnelDielectric(wo.Dot(wm), eta) {
+		wi, reflects = refracts, false
+	}
+	if (wi.Y*wo.Y > 0) != reflects {
+		return wo.Inv(), 0, true // scattered to the wrong side of the surface
+	}
+	return wi, t.PDF(wi, wo), true
 }
 
 func (t Transmit) PDF(wi, wo geom.Dir) float64 {
-	return 1
+	eta := t.eta(wo)
+	if t.Roughness == 0 {
+		n := facing(geom.Up, wo)
+		f := fresnelDielectric(wo.Dot(n), eta)
+		if wi.Equals(wo.Reflect2(n)) {
+			return f
+		}
+		if dir, ok := refracted(wo, n, eta); ok && wi.Equals(dir) {
+			return 1 - f
+		}
+		return 0
+	}
+	wm, ok := t.half(wi, wo)
+	if !ok {
+		return 0
+	}
+	f := fresnelDielectric(wo.Dot(wm), eta)
+	pdf := ggxD(math.Abs(wm.Y), t.Roughness) * math.Abs(wm.Y) // of choosing wm
+	if wi.Y*wo.Y > 0 {
+		return f * pdf / (4 * wo.Dot(wm))
+	}
+	denom := wo.Dot(wm) + eta*wi.Dot(wm)
+	return (1 - f) * pdf * eta * eta * math.Abs(wi.Dot(wm)) / (denom * denom)
 }
 
 func (t Transmit) Eval(wi, wo geom.Dir) rgb.Energy {
-	ior := fresnelToRefractiveIndex(t.Specular)
-	dir := refract(wo.Inv(), geom.Up, ior)
-	if !wi.Equals(dir) {
+	eta := t.eta(wo)
+	if t.Roughness == 0 {
+		return rgb.White.Scaled(t.PDF(wi, wo) * t.Multiplier)
+	}
+	wm, ok := t.half(wi, wo)
+	if !ok {
 		return rgb.Black
 	}
-	return rgb.White.Scaled(t.Multiplier)
+	f := fresnelDielectric(wo.Dot(wm), eta)
+	dg := ggxD(math.Abs(wm.Y), t.Roughness) * smithGGX(wo, geom.Up, t.Roughness) * smithGGX(wi, geom.Up, t.Roughness)
+	if wi.Y*wo.Y > 0 {
+		return rgb.White.Scaled(f * dg / (4 * math.Abs(wo.Y)) * t.Multiplier)
+	}
+	denom := wo.Dot(wm) + eta*wi.Dot(wm)
+	r := (1 - f) * dg * eta * eta * math.Abs(wi.Dot(wm)) * wo.Dot(wm) / (math.Abs(wo.Y) * denom * denom)
+	return rgb.White.Scaled(r * t.Multiplier)
 }
 
-// https://www.scratchapixel.com/lessons/3d-basic-rendering/introduction-to-shading/reflection-refraction-fresnel
-// https://www.bramz.net/data/writings/reflection_transmission.pdf
-func refract(in, normal geom.Dir, ior float64) geom.Dir {
-	var n geom.Dir
-	var eta float64
-	cosi := in.Dot(normal)
-	if cosi >= 0 {
-		eta = ior / 1
-		n = normal.Inv()
+// eta returns the ratio of the index of refraction across the boundary from wo to the index on wo's side.
+// https://docs.blender.org/manual/en/dev/render/cycles/nodes/types/shaders/principled.html
+// http://www.visual-barn.com/2017/03/14/f0-converting-substance-fresnel-vray-values/
+func (t Transmit) eta(wo geom.Dir) float64 {
+	ior := (1 + math.Sqrt(t.Specular)) / (1 - math.Sqrt(t.Specular))
+	if wo.Y < 0 {
+		return 1 / ior
+	}
+	return ior
+}
+
+// half returns the normal, on wo's side of the surface, of the microfacet that reflects or refracts light between wi and wo.
+// It's not ok if no microfacet could.
+func (t Transmit) half(wi, wo geom.Dir) (geom.Dir, bool) {
+	if wo.Y == 0 || wi.Y == 0 {
+		return wo, false
+	}
+	var wm geom.Dir
+	var ok bool
+	if wi.Y*wo.Y > 0 {
+		wm, ok = geom.Vec(wo).Plus(geom.Vec(wi)).Unit()
 	} else {
-		cosi = -cosi
-		eta = 1 / ior
-		n = normal
+		wm, ok = geom.Vec(wo).Plus(wi.Scaled(t.eta(wo))).Unit()
+	}
+	if !ok {
+		return wm, false
 	}
-	k := 1 - eta*eta*(1-cosi*cosi)
-	if k < 0 {
-		return in.Inv().Reflect2(n)
+	wm = facing(wm, facing(geom.Up, wo))
+	if wm.Dot(wo) <= 0 || (wi.Y*wo.Y < 0 && wm.Dot(wi) >= 0) {
+		return wm, false
 	}
-	dir, _ := in.Scaled(eta).Plus(n.Scaled(eta*cosi - math.Sqrt(k))).Unit()
-	return dir
+	return wm, true
 }
 
-// https://docs.blender.org/manual/en/dev/render/cycles/nodes/types/shaders/principled.html
-// http://www.visual-barn.com/2017/03/14/f0-converting-substance-fresnel-vray-values/
-func fresnelToRefractiveIndex(f float64) float64 {
-	return (1 + math.Sqrt(f)) / (1 - math.Sqrt(f))
+// facing returns n or its inverse, whichever is on the same side as w.
+func facing(n, w geom.Dir) geom.Dir {
+	if n.Dot(w) < 0 {
+		return n.Inv()
+	}
+	return n
+}
+
+// refracted returns the direction that light from wo comes from, through a boundary with normal n (facing wo),
+// into a material whose index of refraction is eta times the index on wo's side.
+// It's not ok if the light would be totally internally reflected.
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Specular_Reflection_and_Transmission
+func refracted(wo, n geom.Dir, eta float64) (geom.Dir, bool) {
+	cosI := wo.Dot(n)
+	sin2T := (1 - cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return wo, false
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	dir, ok := wo.Inv().Scaled(1 / eta).Plus(n.Scaled(cosI/eta - cosT)).Unit()
+	return dir, ok
+}
+
+// fresnelDielectric returns the fraction of unpolarized light that a dielectric boundary reflects,
+// where cosI is the cosine of the angle of incidence and eta is the ratio of the indices of refraction across it.
+// https://en.wikipedia.org/wiki/Fresnel_equations
+func fresnelDielectric(cosI, eta float64) float64 {
+	sin2T := (1 - cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return 1 // total internal reflection
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	rs := (cosI - eta*cosT) / (cosI + eta*cosT)
+	rp := (eta*cosI - cosT) / (eta*cosI + cosT)
+	return (rs*rs + rp*rp) / 2
 }
@@ -18,9 +18,15 @@ type SLR struct {
 	FStop  float64
 	Focus  float64
 
+	// Shutter is the fraction of each frame, from its start, that the shutter stays open.
+	// Rays are cast at random times while it's open, blurring anything that moves.
+	Shutter float64
+
 	trans    *geom.Mtx
 	position geom.Vec
 	target   geom.Vec
+	end      *geom.Mtx           // at the end of the frame, if the camera moves
+	path     *geom.Interpolation // from trans to end
 }
 
 // NewSLR constructs a new camera with 35mm sensor full-frame / 50mm lens defaults.
@@ -52,6 +58,14 @@ func (s *SLR) MoveTo(pos geom.Vec) *SLR {
 	return s
 }
 
+// MoveDuring moves a Camera during each frame, from its position and target at the start
+// to a new position and target at the end, for effects like camera shake.
+func (s *SLR) MoveDuring(pos, target geom.Vec) *SLR {
+	s.end = geom.LookMatrix(pos, target)
+	s.transform()
+	return s
+}
+
 func (s *SLR) Ray(x, y, width, height float64, rnd *rand.Rand) *geom.Ray {
 	targetDist := s.target.Minus(s.position).Len()
 	u := x / width
@@ -71,12 +85,22 @@ func (s *SLR) Ray(x, y, width, height float64, rnd *rand.Rand) *geom.Ray {
 	focalPt := geom.Vec(straight).Scaled(focusDist) // TODO: is this creating a curved focal plane? need to project along the center axis?
 	lensPt := s.aperturePoint(rnd)
 	refracted, _ := focalPt.Minus(lensPt).Unit()
-	ray := geom.NewRay(lensPt, refracted)
+	time := 0.0
+	if s.Shutter > 0 {
+		time = rnd.Float64() * s.Shutter
+	}
+	ray := geom.NewRayAt(lensPt, refracted, time)
+	if s.path != nil {
+		return s.path.At(time).MultRay(ray)
+	}
 	return s.trans.MultRay(ray)
 }
 
 func (s *SLR) transform() {
 	s.trans = geom.LookMatrix(s.position, s.target)
+	if s.end != nil {
+		s.path = geom.NewInterpolation(s.trans, s.end)
+	}
 }
 
 func (s *SLR) sensorPoint(u, v, focusDist float64) geom.Vec {
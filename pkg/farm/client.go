@@ -9,7 +9,7 @@ import (
 )
 
 func Render(scene *render.Scene, url string, w, h, depth int, direct bool) error {
-	frame := scene.Render(w, h, depth, direct)
+	frame := scene.Render(w, h, depth, direct, 0)
 	defer frame.Stop()
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
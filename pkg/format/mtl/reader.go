@@ -59,7 +59,13 @@ func Read(r io.Reader, dir string) map[string]*material.Mapped {
 		emit         = "ke"
 		refraction   = "ni"
 		metal        = "pm"
+		sheen        = "ps"
+		clearcoat    = "pc"
+		clearRough   = "pcr"
 		normal       = "norm"
+		bump         = "bump"
+		bumpMap      = "map_bump"
+		displace     = "disp"
 	)
 	scanner := bufio.NewScanner(r)
 	lib := make(map[string]*material.Mapped)
@@ -120,15 +126,63 @@ func Read(r io.Reader, dir string) map[string]*material.Mapped {
 			if m, err := strconv.ParseFloat(args[0], 64); err == nil {
 				lib[current].Base.Metalness = m
 			}
+		case sheen:
+			if s, err := strconv.ParseFloat(args[0], 64); err == nil {
+				lib[current].Base.Sheen = s
+			}
+		case clearcoat:
+			if c, err := strconv.ParseFloat(args[0], 64); err == nil {
+				lib[current].Base.Clearcoat = c
+			}
+		case clearRough:
+			if r, err := strconv.ParseFloat(args[0], 64); err == nil {
+				lib[current].Base.ClearcoatRoughness = r
+			}
 		case normal:
 			f := filepath.Join(dir, strings.Join(args, " "))
 			lib[current].Normal = readTexture(f)
+		case bump, bumpMap:
+			name, opts := mapArgs(args)
+			lib[current].Bump = readTexture(filepath.Join(dir, name))
+			if bm := opts["-bm"]; len(bm) > 0 {
+				lib[current].BumpScale = bm[0]
+			}
+		case displace:
+			name, opts := mapArgs(args)
+			lib[current].Displace = readTexture(filepath.Join(dir, name))
+			if mm := opts["-mm"]; len(mm) > 1 {
+				lib[current].DisplaceScale = mm[1]
+			}
 		}
 	}
 
 	return lib
 }
 
+// mapArgs splits the arguments of a texture map into its filename and its numeric options, like -bm 0.5.
+// http://paulbourke.net/dataformats/mtl/
+func mapArgs(args []string) (name string, opts map[string][]float64) {
+	opts = make(map[string][]float64)
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") {
+		opt := strings.ToLower(args[0])
+		args = args[1:]
+		switch opt {
+		case "-blendu", "-blendv", "-cc", "-clamp", "-imfchan", "-type":
+			args = args[1:] // a word, like "on"
+			continue
+		}
+		for len(args) > 1 {
+			f, err := strconv.ParseFloat(args[0], 64)
+			if err != nil {
+				break
+			}
+			opts[opt] = append(opts[opt], f)
+			args = args[1:]
+		}
+	}
+	return strings.Join(args, " "), opts
+}
+
 // https://www.allegorithmic.com/system/files/software/download/build/PBR_Guide_Vol.1.pdf
 func fresnel0(ior float64) float64 {
 	return math.Pow(ior-1, 2) / math.Pow(ior+1, 2)
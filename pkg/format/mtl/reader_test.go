@@ -0,0 +1,39 @@
+package mtl
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadMaps(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"bump.png", "height.png"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	lib := Read(strings.NewReader(`
+newmtl rock
+map_bump -bm 0.5 bump.png
+disp -mm 0 2 height.png
+`), dir)
+	rock := lib["rock"]
+	if rock == nil {
+		t.Fatal("expected a rock material")
+	}
+	if rock.Bump == nil || rock.BumpScale != 0.5 {
+		t.Errorf("expected a bump map scaled by 0.5, got %v scaled by %v", rock.Bump != nil, rock.BumpScale)
+	}
+	if rock.Displace == nil || rock.DisplaceScale != 2 {
+		t.Errorf("expected a displacement map scaled by 2, got %v scaled by %v", rock.Displace != nil, rock.DisplaceScale)
+	}
+}
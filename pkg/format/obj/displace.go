@@ -0,0 +1,152 @@
+package obj
+
+import (
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// Displace splits every face into four, levels times over,
+// and then pushes each vertex out along its normal by the displacement map of its faces' materials.
+// Vertices at the same point move together, so the mesh doesn't crack where they're split
+// by texture seams or hard edges, and their normals are found again from the displaced faces.
+// Meshes without displacement maps are left alone.
+func (m *Mesh) Displace(levels int) *Mesh {
+	if !m.displaced() {
+		return m
+	}
+	for i := 0; i < levels; i++ {
+		m.split()
+	}
+	m.displace()
+	m.polys = make([]polygon, len(m.faces))
+	for i, f := range m.faces {
+		m.polys[i] = polygon{verts: []int{f.Verts[0], f.Verts[1], f.Verts[2]}, mat: f.Mat}
+	}
+	return m
+}
+
+// displaced returns whether any of the mesh's materials has a displacement map.
+func (m *Mesh) displaced() bool {
+	for i := range m.mats {
+		if mat, ok := m.material(i).(*material.Mapped); ok && mat.Displace != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// split splits each face into four at the midpoints of its edges.
+func (m *Mesh) split() {
+	mids := make(map[[2]int]int)
+	points, base := make(map[[2]int]int), m.pointCount()
+	mid := func(a, b int) int {
+		if a > b {
+			a, b = b, a
+		}
+		if i, ok := mids[[2]int{a, b}]; ok {
+			return i
+		}
+		va, vb := m.verts[a], m.verts[b]
+		v := surface.Vertex{Point: va.Point.Lerp(vb.Point, 0.5), Texture: va.Texture.Lerp(vb.Texture, 0.5)}
+		if va.Normal != (geom.Dir{}) && vb.Normal != (geom.Dir{}) {
+			v.Normal, _ = geom.Vec(va.Normal).Plus(geom.Vec(vb.Normal)).Unit()
+		}
+		edge := sorted(m.points[a], m.points[b])
+		p, ok := points[edge]
+		if !ok {
+			p = base + len(points)
+			points[edge] = p
+		}
+		m.verts = append(m.verts, v)
+		m.points = append(m.points, p)
+		mids[[2]int{a, b}] = len(m.verts) - 1
+		return len(m.verts) - 1
+	}
+	faces := make([]surface.Face, 0, len(m.faces)*4)
+	for _, f := range m.faces {
+		a, b, c := f.Verts[0], f.Verts[1], f.Verts[2]
+		ab, bc, ca := mid(a, b), mid(b, c), mid(c, a)
+		faces = append(faces,
+			surface.Face{Verts: [3]int{a, ab, ca}, Mat: f.Mat},
+			surface.Face{Verts: [3]int{ab, b, bc}, Mat: f.Mat},
+			surface.Face{Verts: [3]int{ca, bc, c}, Mat: f.Mat},
+			surface.Face{Verts: [3]int{ab, bc, ca}, Mat: f.Mat},
+		)
+	}
+	m.faces = faces
+}
+
+// displace moves each vertex along its normal by the displacement of the faces around it.
+// Vertices without normals move along the average normal of the faces around their point, and stay flat.
+func (m *Mesh) displace() {
+	around := make(map[geom.Vec]geom.Vec) // area-weighted normals of the faces at each point
+	dist := make([]float64, len(m.verts))
+	count := make([]int, len(m.verts))
+	for _, f := range m.faces {
+		n := faceNormal(m.verts, f)
+		mat, _ := m.material(f.Mat).(*material.Mapped)
+		for _, i := range f.Verts {
+			around[m.verts[i].Point] = around[m.verts[i].Point].Plus(n)
+			if mat != nil {
+				dist[i] += mat.Displacement(m.verts[i].Texture.X, m.verts[i].Texture.Y)
+			}
+			count[i]++
+		}
+	}
+	moved := make(map[geom.Vec]geom.Vec)
+	shared := make(map[geom.Vec]int)
+	for i, v := range m.verts {
+		if count[i] == 0 {
+			continue
+		}
+		n := v.Normal
+		if n == (geom.Dir{}) {
+			n, _ = around[v.Point].Unit()
+		}
+		moved[v.Point] = moved[v.Point].Plus(n.Scaled(dist[i] / float64(count[i])))
+		shared[v.Point]++
+	}
+	before := make([]surface.Vertex, len(m.verts))
+	copy(before, m.verts)
+	for i, v := range m.verts {
+		if shared[v.Point] > 0 {
+			m.verts[i].Point = v.Point.Plus(moved[v.Point].Scaled(1 / float64(shared[v.Point])))
+		}
+	}
+	// vertices that shared a point and a normal before share a normal after
+	type smooth struct {
+		pt geom.Vec
+		n  geom.Dir
+	}
+	normals := make(map[smooth]geom.Vec)
+	for _, f := range m.faces {
+		n := faceNormal(m.verts, f)
+		for _, i := range f.Verts {
+			v := before[i]
+			if v.Normal == (geom.Dir{}) {
+				continue
+			}
+			key := smooth{v.Point, v.Normal}
+			if n.Dot(geom.Vec(v.Normal)) < 0 {
+				normals[key] = normals[key].Minus(n) // wound the other way
+			} else {
+				normals[key] = normals[key].Plus(n)
+			}
+		}
+	}
+	for i, v := range before {
+		if v.Normal == (geom.Dir{}) {
+			continue
+		}
+		if n, ok := normals[smooth{v.Point, v.Normal}].Unit(); ok {
+			m.verts[i].Normal = n
+		}
+	}
+}
+
+// faceNormal returns the normal of f scaled by twice its area.
+func faceNormal(verts []surface.Vertex, f surface.Face) geom.Vec {
+	a, b, c := verts[f.Verts[0]].Point, verts[f.Verts[1]].Point, verts[f.Verts[2]].Point
+	return b.Minus(a).Cross(c.Minus(a))
+}
@@ -0,0 +1,47 @@
+package obj
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/material"
+)
+
+const quad = `
+v 0 0 0
+v 1 0 0
+v 1 0 1
+v 0 0 1
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 1 0
+f 1/1/1 4/4/1 3/3/1 2/2/1
+`
+
+func TestDisplaceFlat(t *testing.T) {
+	const scale = 0.25
+	white := image.NewGray(image.Rect(0, 0, 4, 4))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	mat := material.NewMapped(&material.Uniform{})
+	mat.Displace, mat.DisplaceScale = white, scale
+
+	m := Read(strings.NewReader(quad), "").SetMaterial(mat).Displace(2)
+	if n := m.Len(); n != 2*4*4 {
+		t.Errorf("expected %v faces, got %v", 2*4*4, n)
+	}
+	for _, v := range m.verts {
+		p := v.Point
+		if math.Abs(p.Y-scale) > 1e-9 || p.X < 0 || p.X > 1 || p.Z < 0 || p.Z > 1 {
+			t.Errorf("expected %v to move up by %v", p, scale)
+		}
+		if math.Abs(v.Normal.Y-1) > 1e-9 {
+			t.Errorf("expected the normal at %v to stay up, got %v", p, v.Normal)
+		}
+	}
+}
@@ -25,3 +25,7 @@ func (m *Material) Light() rgb.Energy {
 func (m *Material) Transmit() rgb.Energy {
 	return rgb.Black
 }
+
+func (m *Material) Medium() *render.Medium {
+	return nil
+}
@@ -6,10 +6,31 @@ import (
 	"github.com/hunterloftis/pbr2/pkg/surface"
 )
 
+// Mesh holds the vertices, faces, and materials read from an .obj file.
+// Its transforms and material override are applied when it's turned into Surfaces.
 type Mesh struct {
-	Triangles []*surface.Triangle
-	mtx       *geom.Mtx
-	mat       *surface.Material
+	verts  []surface.Vertex
+	points []int     // the .obj position of each vertex, which vertices split by seams and hard edges share
+	polys  []polygon // faces as they were read, before they're split into triangles
+	faces  []surface.Face
+	mats   []surface.Material
+	mtx    *geom.Mtx
+	mat    *surface.Material
+}
+
+// polygon is a face of any number of vertices.
+type polygon struct {
+	verts []int
+	mat   int
+}
+
+// triangles splits p into a fan of triangles around its first vertex.
+func (p polygon) triangles() []surface.Face {
+	faces := make([]surface.Face, 0, len(p.verts)-2)
+	for i := 2; i < len(p.verts); i++ {
+		faces = append(faces, surface.Face{Verts: [3]int{p.verts[0], p.verts[i-1], p.verts[i]}, Mat: p.mat})
+	}
+	return faces
 }
 
 func NewMesh() *Mesh {
@@ -18,23 +39,98 @@ func NewMesh() *Mesh {
 	}
 }
 
-func (m *Mesh) Surfaces() []render.Surface {
-	ss := make([]render.Surface, 0)
-	for _, t := range m.Triangles {
-		t2 := t.Transformed(m.mtx)
-		if m.mat != nil {
-			t2.Mat = *m.mat
+// Triangles returns a Triangle for each face, with the mesh's transforms and material override applied.
+func (m *Mesh) Triangles() []*surface.Triangle {
+	ts := make([]*surface.Triangle, 0, len(m.faces))
+	for _, f := range m.faces {
+		a, b, c := m.vertex(f.Verts[0]), m.vertex(f.Verts[1]), m.vertex(f.Verts[2])
+		t := surface.NewTriangle(a.Point, b.Point, c.Point, m.material(f.Mat))
+		if a.Normal != (geom.Dir{}) && b.Normal != (geom.Dir{}) && c.Normal != (geom.Dir{}) {
+			t.SetNormals(a.Normal, b.Normal, c.Normal)
 		}
-		ss = append(ss, t2)
+		t.SetTexture(a.Texture, b.Texture, c.Texture)
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// Surfaces returns a Triangle for each face.
+func (m *Mesh) Surfaces() []render.Surface {
+	ts := m.Triangles()
+	ss := make([]render.Surface, len(ts))
+	for i, t := range ts {
+		ss[i] = t
 	}
 	return ss
 }
 
+// Indexed returns a single surface.Mesh of every face, which shares vertices between faces
+// and needs much less memory than Surfaces.
+// Compact meshes store vertices at single precision.
+func (m *Mesh) Indexed(compact bool) *surface.Mesh {
+	verts := make([]surface.Vertex, len(m.verts))
+	for i := range verts {
+		verts[i] = m.vertex(i)
+	}
+	mats := make([]surface.Material, len(m.mats))
+	for i := range mats {
+		mats[i] = m.material(i)
+	}
+	return surface.NewMesh(verts, m.faces, mats, compact)
+}
+
+// Len returns the number of faces in the mesh.
+func (m *Mesh) Len() int {
+	return len(m.faces)
+}
+
+// vertex returns vertex i, transformed.
+func (m *Mesh) vertex(i int) surface.Vertex {
+	v := m.verts[i]
+	v.Point = m.mtx.MultPoint(v.Point)
+	if v.Normal != (geom.Dir{}) {
+		v.Normal = m.mtx.MultDir(v.Normal)
+	}
+	return v
+}
+
+// pointCount returns the number of .obj positions that the mesh's vertices are at.
+func (m *Mesh) pointCount() int {
+	n := 0
+	for _, p := range m.points {
+		if p >= n {
+			n = p + 1
+		}
+	}
+	return n
+}
+
+func (m *Mesh) material(i int) surface.Material {
+	if m.mat != nil {
+		return *m.mat
+	}
+	return m.mats[i]
+}
+
 func (m *Mesh) Bounds() (*geom.Bounds, []render.Surface) {
 	ss := m.Surfaces()
 	return surface.BoundsAround(ss), ss
 }
 
+// extent returns the bounds of the transformed vertices.
+func (m *Mesh) extent() *geom.Bounds {
+	if len(m.verts) == 0 {
+		return geom.NewBounds(geom.Vec{}, geom.Vec{})
+	}
+	min := m.vertex(0).Point
+	max := min
+	for i := range m.verts {
+		pt := m.vertex(i).Point
+		min, max = min.Min(pt), max.Max(pt)
+	}
+	return geom.NewBounds(min, max)
+}
+
 func (m *Mesh) SetMaterial(mat surface.Material) *Mesh {
 	m.mat = &mat
 	return m
@@ -52,7 +148,7 @@ func (m *Mesh) Rotate(v geom.Vec) *Mesh {
 
 func (m *Mesh) MoveTo(pt, anchor geom.Vec) *Mesh {
 	inv := m.mtx.Inverse() // global to local
-	b, _ := m.Bounds()
+	b := m.extent()
 	size := b.Max.Minus(b.Min).Scaled(0.5)
 	origin := b.Center.Plus(anchor.By(size))
 	dist := pt.Minus(origin)
@@ -0,0 +1,19 @@
+package obj
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+)
+
+func TestMeshTriangles(t *testing.T) {
+	m := Read(strings.NewReader(cube), "").Scale(geom.Vec{2, 2, 2})
+	ts := m.Triangles()
+	if len(ts) != m.Len() {
+		t.Fatalf("expected %v triangles, got %v", m.Len(), len(ts))
+	}
+	if b := ts[0].Bounds(); b.Max.X > 2 || b.Max.Y > 2 || b.Max.Z > 2 || b.Max.Minus(b.Min).Len() < 2 {
+		t.Errorf("expected a triangle scaled into the cube from 0 to 2, got bounds from %v to %v", b.Min, b.Max)
+	}
+}
@@ -33,13 +33,13 @@ func ReadFile(filename string, recursive bool) (*Mesh, error) {
 
 func ReadMaterials(mesh *Mesh) {
 	lib := make(map[string]*material.Mapped)
-	for _, t := range mesh.Triangles {
-		if m, ok := t.Mat.(*Material); ok {
+	for i, mat := range mesh.mats {
+		if m, ok := mat.(*Material); ok {
 			if lib[m.Name] == nil {
 				readLibraries(lib, m.Files)
 			}
 			if lib[m.Name] != nil {
-				t.Mat = lib[m.Name]
+				mesh.mats[i] = lib[m.Name]
 			}
 		}
 	}
@@ -58,30 +58,51 @@ func readLibraries(lib map[string]*material.Mapped, files []string) {
 }
 
 type tablegroup struct {
-	vv []geom.Vec
-	nn []geom.Dir
-	tt []geom.Vec
+	vv    []geom.Vec
+	nn    []geom.Dir
+	tt    []geom.Vec
+	verts map[[3]int]int // mesh vertex of each combination of point, texture, and normal
+	mats  map[*Material]int
 }
 
-func (t *tablegroup) vert(i int) geom.Vec {
-	if i < 1 {
-		return t.vv[len(t.vv)+i]
+// vertex returns the index of the mesh vertex made from the 1-based (or negative, relative) indices
+// of a point, texture coordinate, and normal; 0 for texture or normal means none.
+func (t *tablegroup) vertex(m *Mesh, v, vt, vn int) (int, error) {
+	key := [3]int{absolute(v, len(t.vv)), absolute(vt, len(t.tt)), absolute(vn, len(t.nn))}
+	if key[0] < 1 || key[0] > len(t.vv) || key[1] > len(t.tt) || key[2] > len(t.nn) || key[1] < 0 || key[2] < 0 {
+		return 0, fmt.Errorf("face index out of range (%v/%v/%v)", v, vt, vn)
 	}
-	return t.vv[i-1]
+	if i, ok := t.verts[key]; ok {
+		return i, nil
+	}
+	vert := surface.Vertex{Point: t.vv[key[0]-1]}
+	if key[1] > 0 {
+		vert.Texture = t.tt[key[1]-1]
+	}
+	if key[2] > 0 {
+		vert.Normal = t.nn[key[2]-1]
+	}
+	m.verts = append(m.verts, vert)
+	m.points = append(m.points, key[0]-1)
+	t.verts[key] = len(m.verts) - 1
+	return len(m.verts) - 1, nil
 }
 
-func (t *tablegroup) norm(i int) geom.Dir {
-	if i < 1 {
-		return t.nn[len(t.nn)+i]
+// material returns the index of mat in the mesh.
+func (t *tablegroup) material(m *Mesh, mat *Material) int {
+	if i, ok := t.mats[mat]; ok {
+		return i
 	}
-	return t.nn[i-1]
+	m.mats = append(m.mats, mat)
+	t.mats[mat] = len(m.mats) - 1
+	return len(m.mats) - 1
 }
 
-func (t *tablegroup) tex(i int) geom.Vec {
-	if i < 1 {
-		return t.tt[len(t.tt)+i]
+func absolute(i, size int) int {
+	if i < 0 {
+		return size + i + 1
 	}
-	return t.tt[i-1]
+	return i
 }
 
 func Read(r io.Reader, dir string) *Mesh {
@@ -95,7 +116,7 @@ func Read(r io.Reader, dir string) *Mesh {
 	)
 
 	mesh := NewMesh()
-	table := &tablegroup{}
+	table := &tablegroup{verts: make(map[[3]int]int), mats: make(map[*Material]int)}
 	mat := &Material{}
 	mats := make(map[string]*Material)
 	libs := make([]string, 0)
@@ -129,11 +150,12 @@ func Read(r io.Reader, dir string) *Mesh {
 			}
 			table.tt = append(table.tt, t)
 		case face:
-			tris, err := newTriangles(args, table, mat)
+			p, err := newPolygon(args, table, mesh, mat)
 			if err != nil {
 				panic(err)
 			}
-			mesh.Triangles = append(mesh.Triangles, tris...)
+			mesh.polys = append(mesh.polys, p)
+			mesh.faces = append(mesh.faces, p.triangles()...)
 		case library:
 			libs = append(libs, strings.Join(args, " "))
 		case material:
@@ -180,47 +202,33 @@ func newMaterial(args []string, mats map[string]*Material) *Material {
 	return mats[name]
 }
 
-func newTriangles(args []string, table *tablegroup, mat *Material) ([]*surface.Triangle, error) {
+func newPolygon(args []string, table *tablegroup, mesh *Mesh, mat *Material) (polygon, error) {
 	size := len(args)
 	if size < 3 {
-		return nil, fmt.Errorf("face requires at least 3 vertices (contains %v)", size)
+		return polygon{}, fmt.Errorf("face requires at least 3 vertices (contains %v)", size)
 	}
-	verts := make([]geom.Vec, 0)
-	norms := make([]geom.Dir, 0)
-	texes := make([]geom.Vec, 0)
+	verts := make([]int, 0, size)
 	for _, arg := range args {
 		fields := strings.Split(arg, "/")
-		if i, err := parseInt(fields[0]); err == nil {
-			verts = append(verts, table.vert(i))
-		}
-		if len(fields) < 2 {
-			continue
-		}
-		if i, err := parseInt(fields[1]); err == nil {
-			texes = append(texes, table.tex(i))
+		var ii [3]int
+		for k := 0; k < len(fields) && k < 3; k++ {
+			if i, err := parseInt(fields[k]); err == nil {
+				ii[k] = i
+			}
 		}
-		if len(fields) < 3 {
+		if ii[0] == 0 {
 			continue
 		}
-		if i, err := parseInt(fields[2]); err == nil {
-			norms = append(norms, table.norm(i))
+		v, err := table.vertex(mesh, ii[0], ii[1], ii[2])
+		if err != nil {
+			return polygon{}, err
 		}
+		verts = append(verts, v)
 	}
 	if len(verts) != size {
-		return nil, fmt.Errorf("face vertex size != arg list size")
-	}
-	tris := make([]*surface.Triangle, 0)
-	for i := 2; i < size; i++ {
-		tri := surface.NewTriangle(verts[0], verts[i-1], verts[i], mat)
-		if len(norms) == size {
-			tri.SetNormals(norms[0], norms[i-1], norms[i])
-		}
-		if len(texes) == size {
-			tri.SetTexture(texes[0], texes[i-1], texes[i])
-		}
-		tris = append(tris, tri)
+		return polygon{}, fmt.Errorf("face vertex size != arg list size")
 	}
-	return tris, nil
+	return polygon{verts: verts, mat: table.material(mesh, mat)}, nil
 }
 
 func parseInt(str string) (int, error) {
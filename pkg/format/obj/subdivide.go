@@ -0,0 +1,325 @@
+package obj
+
+import (
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// Subdivide smooths the mesh by splitting its faces, levels times over,
+// with Loop subdivision if every face is a triangle and Catmull-Clark subdivision otherwise.
+// Faces are joined wherever they share .obj positions, even where their texture coordinates or normals differ.
+// Edges stay sharp where the mesh ends or where its faces have different normals (hard edges).
+// Texture coordinates are interpolated within each face, so UV seams stay where they are.
+// Normals are found again from the subdivided faces.
+// https://en.wikipedia.org/wiki/Loop_subdivision_surface
+// https://en.wikipedia.org/wiki/Catmull%E2%80%93Clark_subdivision_surface
+func (m *Mesh) Subdivide(levels int) *Mesh {
+	if levels < 1 {
+		return m
+	}
+	loop := true
+	for _, p := range m.polys {
+		if len(p.verts) != 3 {
+			loop = false
+		}
+	}
+	for i := 0; i < levels; i++ {
+		if loop {
+			newTopology(m).loop()
+		} else {
+			newTopology(m).catmullClark()
+		}
+	}
+	m.smooth()
+	m.faces = m.faces[:0]
+	for _, p := range m.polys {
+		m.faces = append(m.faces, p.triangles()...)
+	}
+	return m
+}
+
+// topology is how the polygons of a Mesh connect through their .obj positions (points).
+type topology struct {
+	m      *Mesh
+	pos    []geom.Vec     // of each point
+	edges  []edge         // between points
+	index  map[[2]int]int // of the edge between two points
+	around [][]int        // edges at each point
+	polys  [][]int        // polygons at each point
+}
+
+type edge struct {
+	a, b    int   // points, a < b
+	polys   []int // that share the edge
+	normals [2]geom.Dir
+	sharp   bool
+}
+
+func newTopology(m *Mesh) *topology {
+	n := m.pointCount()
+	t := &topology{
+		m:      m,
+		pos:    make([]geom.Vec, n),
+		index:  make(map[[2]int]int),
+		around: make([][]int, n),
+		polys:  make([][]int, n),
+	}
+	for i, v := range m.verts {
+		t.pos[m.points[i]] = v.Point
+	}
+	for i, p := range m.polys {
+		for j, v := range p.verts {
+			w := p.verts[(j+1)%len(p.verts)]
+			a, b := m.points[v], m.points[w]
+			t.polys[a] = append(t.polys[a], i)
+			if a == b {
+				continue
+			}
+			normals := [2]geom.Dir{m.verts[v].Normal, m.verts[w].Normal}
+			if a > b {
+				normals[0], normals[1] = normals[1], normals[0]
+			}
+			key := sorted(a, b)
+			k, ok := t.index[key]
+			if !ok {
+				k = len(t.edges)
+				t.index[key] = k
+				t.edges = append(t.edges, edge{a: key[0], b: key[1], normals: normals})
+				t.around[a] = append(t.around[a], k)
+				t.around[b] = append(t.around[b], k)
+			}
+			e := &t.edges[k]
+			e.polys = append(e.polys, i)
+			e.sharp = e.sharp || e.normals != normals
+		}
+	}
+	for i := range t.edges {
+		if len(t.edges[i].polys) != 2 {
+			t.edges[i].sharp = true
+		}
+	}
+	return t
+}
+
+// vertex returns where point p moves to.
+// Points on two sharp edges move along them, and points on more, or at the corners of open meshes,
+// stay still; the rest move to wherever smooth returns.
+func (t *topology) vertex(p int, smooth func() geom.Vec) geom.Vec {
+	var ends []geom.Vec
+	for _, i := range t.around[p] {
+		if e := t.edges[i]; e.sharp {
+			ends = append(ends, t.pos[e.other(p)])
+		}
+	}
+	switch {
+	case len(t.around[p]) <= 2 || len(ends) > 2:
+		return t.pos[p]
+	case len(ends) == 2:
+		return t.pos[p].Scaled(0.75).Plus(ends[0].Plus(ends[1]).Scaled(0.125))
+	}
+	return smooth()
+}
+
+// catmullClark splits each n-sided polygon into n quads around a new point at its center.
+func (t *topology) catmullClark() {
+	m := t.m
+	centers := make([]geom.Vec, len(m.polys))
+	for i, p := range m.polys {
+		for _, v := range p.verts {
+			centers[i] = centers[i].Plus(m.verts[v].Point)
+		}
+		centers[i] = centers[i].Scaled(1 / float64(len(p.verts)))
+	}
+	b := newBuilder(len(t.pos) + len(t.edges) + len(m.polys))
+	for i, e := range t.edges {
+		mid := t.pos[e.a].Lerp(t.pos[e.b], 0.5)
+		if !e.sharp {
+			mid = mid.Lerp(centers[e.polys[0]].Lerp(centers[e.polys[1]], 0.5), 0.5)
+		}
+		b.pos[len(t.pos)+i] = mid
+	}
+	for i, c := range centers {
+		b.pos[len(t.pos)+len(t.edges)+i] = c
+	}
+	for p := range t.pos {
+		b.pos[p] = t.vertex(p, func() geom.Vec {
+			var q, r geom.Vec
+			for _, i := range t.polys[p] {
+				q = q.Plus(centers[i])
+			}
+			for _, i := range t.around[p] {
+				r = r.Plus(t.pos[t.edges[i].a].Lerp(t.pos[t.edges[i].b], 0.5))
+			}
+			n := float64(len(t.around[p]))
+			q, r = q.Scaled(1/float64(len(t.polys[p]))), r.Scaled(1/n)
+			return q.Plus(r.Scaled(2)).Plus(t.pos[p].Scaled(n - 3)).Scaled(1 / n)
+		})
+	}
+	var polys []polygon
+	for i, p := range m.polys {
+		var tex, norm geom.Vec
+		for _, v := range p.verts {
+			tex = tex.Plus(m.verts[v].Texture)
+			norm = norm.Plus(geom.Vec(m.verts[v].Normal))
+		}
+		center := b.add(len(t.pos)+len(t.edges)+i, tex.Scaled(1/float64(len(p.verts))), label(norm))
+		k := len(p.verts)
+		for j, v := range p.verts {
+			prev, next := p.verts[(j+k-1)%k], p.verts[(j+1)%k]
+			corner := b.add(m.points[v], m.verts[v].Texture, m.verts[v].Normal)
+			polys = append(polys, polygon{verts: []int{corner, t.mid(b, v, next), center, t.mid(b, prev, v)}, mat: p.mat})
+		}
+	}
+	b.apply(m, polys)
+}
+
+// loop splits each triangle into four at the midpoints of its edges.
+func (t *topology) loop() {
+	m := t.m
+	b := newBuilder(len(t.pos) + len(t.edges))
+	for i, e := range t.edges {
+		mid := t.pos[e.a].Lerp(t.pos[e.b], 0.5)
+		if !e.sharp {
+			opposite := t.pos[t.opposite(e.polys[0], e)].Plus(t.pos[t.opposite(e.polys[1], e)])
+			mid = mid.Scaled(0.75).Plus(opposite.Scaled(0.125))
+		}
+		b.pos[len(t.pos)+i] = mid
+	}
+	for p := range t.pos {
+		b.pos[p] = t.vertex(p, func() geom.Vec {
+			var sum geom.Vec
+			for _, i := range t.around[p] {
+				sum = sum.Plus(t.pos[t.edges[i].other(p)])
+			}
+			n := float64(len(t.around[p]))
+			beta := 3 / (8 * n)
+			if n == 3 {
+				beta = 3.0 / 16
+			}
+			return t.pos[p].Scaled(1 - n*beta).Plus(sum.Scaled(beta))
+		})
+	}
+	var polys []polygon
+	for _, p := range m.polys {
+		a, c, e := p.verts[0], p.verts[1], p.verts[2]
+		ac, ce, ea := t.mid(b, a, c), t.mid(b, c, e), t.mid(b, e, a)
+		corner := func(v int) int {
+			return b.add(m.points[v], m.verts[v].Texture, m.verts[v].Normal)
+		}
+		polys = append(polys,
+			polygon{verts: []int{corner(a), ac, ea}, mat: p.mat},
+			polygon{verts: []int{ac, corner(c), ce}, mat: p.mat},
+			polygon{verts: []int{ea, ce, corner(e)}, mat: p.mat},
+			polygon{verts: []int{ac, ce, ea}, mat: p.mat},
+		)
+	}
+	b.apply(m, polys)
+}
+
+// mid adds the vertex at the new point on the edge between vertices v and w.
+func (t *topology) mid(b *builder, v, w int) int {
+	m := t.m
+	i := t.index[sorted(m.points[v], m.points[w])]
+	tex := m.verts[v].Texture.Lerp(m.verts[w].Texture, 0.5)
+	norm := geom.Vec(m.verts[v].Normal).Plus(geom.Vec(m.verts[w].Normal))
+	return b.add(len(t.pos)+i, tex, label(norm))
+}
+
+// opposite returns the point of triangle i that isn't on e.
+func (t *topology) opposite(i int, e edge) int {
+	for _, v := range t.m.polys[i].verts {
+		if p := t.m.points[v]; p != e.a && p != e.b {
+			return p
+		}
+	}
+	return e.a
+}
+
+func (e edge) other(p int) int {
+	if p == e.a {
+		return e.b
+	}
+	return e.a
+}
+
+// builder collects the vertices of a subdivided mesh.
+// Each vertex is a point with a texture coordinate and a normal,
+// which marks the vertices that were split by hard edges until normals are found again.
+type builder struct {
+	pos    []geom.Vec
+	verts  []surface.Vertex
+	points []int
+	index  map[builderKey]int
+}
+
+type builderKey struct {
+	point   int
+	texture geom.Vec
+	normal  geom.Dir
+}
+
+func newBuilder(points int) *builder {
+	return &builder{pos: make([]geom.Vec, points), index: make(map[builderKey]int)}
+}
+
+// add returns the index of the vertex at point p with texture coordinate tex and normal n.
+func (b *builder) add(p int, tex geom.Vec, n geom.Dir) int {
+	key := builderKey{p, tex, n}
+	if i, ok := b.index[key]; ok {
+		return i
+	}
+	b.verts = append(b.verts, surface.Vertex{Point: b.pos[p], Texture: tex, Normal: n})
+	b.points = append(b.points, p)
+	b.index[key] = len(b.verts) - 1
+	return len(b.verts) - 1
+}
+
+// apply replaces the vertices and polygons of m.
+func (b *builder) apply(m *Mesh, polys []polygon) {
+	m.verts, m.points, m.polys = b.verts, b.points, polys
+}
+
+// smooth sets the normal of each vertex to the average normal of the polygons around it
+// that share its point and normal, so hard edges stay hard.
+func (m *Mesh) smooth() {
+	type group struct {
+		point  int
+		normal geom.Dir
+	}
+	sums := make(map[group]geom.Vec)
+	for _, p := range m.polys {
+		var n geom.Vec
+		for _, f := range p.triangles() {
+			n = n.Plus(faceNormal(m.verts, f))
+		}
+		for _, v := range p.verts {
+			g := group{m.points[v], m.verts[v].Normal}
+			if n.Dot(geom.Vec(g.normal)) < 0 {
+				sums[g] = sums[g].Minus(n) // wound the other way
+			} else {
+				sums[g] = sums[g].Plus(n)
+			}
+		}
+	}
+	for i, v := range m.verts {
+		if n, ok := sums[group{m.points[i], v.Normal}].Unit(); ok {
+			m.verts[i].Normal = n
+		}
+	}
+}
+
+// label returns the direction of n, or no direction if n is zero.
+func label(n geom.Vec) geom.Dir {
+	d, ok := n.Unit()
+	if !ok {
+		return geom.Dir{}
+	}
+	return d
+}
+
+func sorted(a, b int) [2]int {
+	if a > b {
+		return [2]int{b, a}
+	}
+	return [2]int{a, b}
+}
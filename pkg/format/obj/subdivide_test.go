@@ -0,0 +1,138 @@
+package obj
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+)
+
+const tetrahedron = `
+v 1 1 1
+v 1 -1 -1
+v -1 1 -1
+v -1 -1 1
+f 1 2 3
+f 1 4 2
+f 1 3 4
+f 2 4 3
+`
+
+const cube = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f 1 4 3 2
+f 5 6 7 8
+f 1 2 6 5
+f 4 8 7 3
+f 1 5 8 4
+f 2 3 7 6
+`
+
+// fold is two strips of two quads that meet at a hard edge along the X axis.
+const fold = `
+v 0 0 0
+v 1 0 0
+v 2 0 0
+v 0 0 1
+v 1 0 1
+v 2 0 1
+v 0 1 0
+v 1 1 0
+v 2 1 0
+vn 0 1 0
+vn 0 0 -1
+f 1//1 4//1 5//1 2//1
+f 2//1 5//1 6//1 3//1
+f 1//2 2//2 8//2 7//2
+f 2//2 3//2 9//2 8//2
+`
+
+// sheet is an open square of two by two quads.
+const sheet = `
+v 0 0 0
+v 1 0 0
+v 2 0 0
+v 0 0 1
+v 1 0 1
+v 2 0 1
+v 0 0 2
+v 1 0 2
+v 2 0 2
+f 1 4 5 2
+f 2 5 6 3
+f 4 7 8 5
+f 5 8 9 6
+`
+
+func TestSubdivideCounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		obj          string
+		points       int
+		polys, faces int
+	}{
+		{"loop", tetrahedron, 4 + 6, 16, 16},
+		{"catmull-clark", cube, 8 + 12 + 6, 24, 48},
+	}
+	for _, test := range tests {
+		m := Read(strings.NewReader(test.obj), "").Subdivide(1)
+		if n := m.pointCount(); n != test.points {
+			t.Errorf("%v: expected %v points, got %v", test.name, test.points, n)
+		}
+		if n := len(m.polys); n != test.polys {
+			t.Errorf("%v: expected %v polygons, got %v", test.name, test.polys, n)
+		}
+		if n := m.Len(); n != test.faces {
+			t.Errorf("%v: expected %v faces, got %v", test.name, test.faces, n)
+		}
+	}
+}
+
+func TestSubdivideHardEdge(t *testing.T) {
+	m := Read(strings.NewReader(fold), "").Subdivide(1)
+	on := make(map[float64]bool)
+	for _, v := range m.verts {
+		if math.Abs(v.Point.Y) < 1e-9 && math.Abs(v.Point.Z) < 1e-9 {
+			on[v.Point.X] = true
+		}
+	}
+	for _, x := range []float64{0, 0.5, 1, 1.5, 2} {
+		if !on[x] {
+			t.Errorf("expected a point at %v on the hard edge, got %v", x, on)
+		}
+	}
+}
+
+func TestSubdivideBoundary(t *testing.T) {
+	m := Read(strings.NewReader(sheet), "").Subdivide(1)
+	corners := map[geom.Vec]bool{{0, 0, 0}: false, {2, 0, 0}: false, {0, 0, 2}: false, {2, 0, 2}: false}
+	edges := 0
+	for _, v := range m.verts {
+		p := v.Point
+		if _, ok := corners[p]; ok {
+			corners[p] = true
+		}
+		if p.Y != 0 || p.X < 0 || p.X > 2 || p.Z < 0 || p.Z > 2 {
+			t.Errorf("%v moved out of the square", p)
+		}
+		if p.X == 0 || p.X == 2 || p.Z == 0 || p.Z == 2 {
+			edges++
+		}
+	}
+	for p, ok := range corners {
+		if !ok {
+			t.Errorf("corner %v moved", p)
+		}
+	}
+	if edges != 16 { // each of the 4 sides is split into 4
+		t.Errorf("expected 16 points on the edges of the square, got %v", edges)
+	}
+}
@@ -81,24 +81,43 @@ func (b *Bounds) Contains(p Vec) bool {
 	return true
 }
 
-// RayFrom inscribes the Bounds within a unit sphere,
-// projects a solid angle disc from that sphere towards the origin,
-// chooses a random point within that disc,
-// and returns a Ray from the origin to the random point.
-// https://marine.rutgers.edu/dmcs/ms552/2009/solidangle.pdf
-func (b *Bounds) ShadowRay(pt Vec, normal Dir, rnd *rand.Rand) (*Ray, float64) {
-	forward, _ := pt.Minus(b.Center).Unit()
-	x, y := RandPointInCircle(b.Radius, rnd) // TODO: push center back along "forward" axis, away from pt
-	right, _ := forward.Cross(Up)
-	up, _ := right.Cross(forward)
-	point := b.Center.Plus(right.Scaled(x)).Plus(up.Scaled(y))
-	diff, _ := point.Minus(pt).Unit()
-	ray := NewRay(pt, diff) // TODO: this should be a convenience method
+// SampleCone chooses a random direction from pt towards the sphere that encloses the Bounds.
+// Directions are distributed uniformly over the solid angle of the sphere,
+// and are returned with their probability density (per steradian).
+// https://www.pbr-book.org/3ed-2018/Light_Transport_I_Surface_Reflection/Sampling_Light_Sources#SamplingSpheres
+func (b *Bounds) SampleCone(pt Vec, rnd *rand.Rand) (Dir, float64) {
+	axis, cosMax := b.cone(pt)
+	cos := 1 - rnd.Float64()*(1-cosMax)
+	sin := math.Sqrt(math.Max(0, 1-cos*cos))
+	phi := 2 * math.Pi * rnd.Float64()
+	right, ok := axis.Cross(Up)
+	if !ok {
+		right = Dir{1, 0, 0}
+	}
+	up, _ := right.Cross(axis)
+	v := right.Scaled(sin * math.Cos(phi)).Plus(up.Scaled(sin * math.Sin(phi))).Plus(axis.Scaled(cos))
+	dir, _ := v.Unit()
+	return dir, 1 / (2 * math.Pi * (1 - cosMax))
+}
 
-	// https://en.wikipedia.org/wiki/Solid_angle#Sun_and_Moon
-	dist := b.Center.Minus(pt).Len()
-	theta := math.Atan2(b.Radius, dist)
-	coverage := math.Max(0, math.Min(1, 1-math.Cos(theta)))
+// ConePDF returns the probability density with which SampleCone chooses dir from pt.
+func (b *Bounds) ConePDF(pt Vec, dir Dir) float64 {
+	axis, cosMax := b.cone(pt)
+	if axis.Dot(dir) < cosMax {
+		return 0
+	}
+	return 1 / (2 * math.Pi * (1 - cosMax))
+}
 
-	return ray, coverage
+// cone returns the axis and the cosine of the half-angle of the cone from pt that encloses the Bounds' sphere.
+// From inside the sphere, the cone covers every direction.
+func (b *Bounds) cone(pt Vec) (axis Dir, cosMax float64) {
+	d := b.Center.Minus(pt)
+	dist := d.Len()
+	if dist <= b.Radius {
+		return Up, -1
+	}
+	axis, _ = d.Unit()
+	sin := b.Radius / dist
+	return axis, math.Sqrt(1 - sin*sin)
 }
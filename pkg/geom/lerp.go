@@ -0,0 +1,140 @@
+package geom
+
+import "math"
+
+// Interpolate returns the transform t of the way from a to b, where t is from 0 to 1.
+// Each matrix is split into a translation, a rotation, and a scale along each axis,
+// which are interpolated separately so that rotating objects don't shrink halfway through their turn.
+// Matrices that scale after rotating (and so shear) aren't decomposed exactly.
+// http://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations.html
+func Interpolate(a, b *Mtx, t float64) *Mtx {
+	return NewInterpolation(a, b).At(t)
+}
+
+// Interpolation blends between two transforms, like Interpolate,
+// after decomposing them once so that each blend only interpolates their parts.
+type Interpolation struct {
+	a, b           *Mtx
+	shiftA, shiftB Vec
+	rotA, rotB     quat
+	scaleA, scaleB Vec
+}
+
+// NewInterpolation returns an Interpolation from a at time 0 to b at time 1.
+func NewInterpolation(a, b *Mtx) *Interpolation {
+	in := Interpolation{a: a, b: b}
+	in.shiftA, in.rotA, in.scaleA = a.decompose()
+	in.shiftB, in.rotB, in.scaleB = b.decompose()
+	return &in
+}
+
+// At returns the transform t of the way from a to b, along with its inverse.
+func (in *Interpolation) At(t float64) *Mtx {
+	if t <= 0 {
+		return in.a
+	}
+	if t >= 1 {
+		return in.b
+	}
+	shift := in.shiftA.Scaled(1 - t).Plus(in.shiftB.Scaled(t))
+	scale := in.scaleA.Scaled(1 - t).Plus(in.scaleB.Scaled(t))
+	r := slerp(in.rotA, in.rotB, t).matrix()
+	m := NewMat(
+		r[0][0]*scale.X, r[0][1]*scale.Y, r[0][2]*scale.Z, shift.X,
+		r[1][0]*scale.X, r[1][1]*scale.Y, r[1][2]*scale.Z, shift.Y,
+		r[2][0]*scale.X, r[2][1]*scale.Y, r[2][2]*scale.Z, shift.Z,
+		0, 0, 0, 1,
+	)
+	// the inverse scales by 1 / scale after rotating back by the transpose of r, after shifting back
+	s := Vec{1 / scale.X, 1 / scale.Y, 1 / scale.Z}
+	back := Vec{
+		-(r[0][0]*shift.X + r[1][0]*shift.Y + r[2][0]*shift.Z) * s.X,
+		-(r[0][1]*shift.X + r[1][1]*shift.Y + r[2][1]*shift.Z) * s.Y,
+		-(r[0][2]*shift.X + r[1][2]*shift.Y + r[2][2]*shift.Z) * s.Z,
+	}
+	inv := NewMat(
+		r[0][0]*s.X, r[1][0]*s.X, r[2][0]*s.X, back.X,
+		r[0][1]*s.Y, r[1][1]*s.Y, r[2][1]*s.Y, back.Y,
+		r[0][2]*s.Z, r[1][2]*s.Z, r[2][2]*s.Z, back.Z,
+		0, 0, 0, 1,
+	)
+	m.inv, inv.inv = inv, m
+	return m
+}
+
+// quat is a unit quaternion representing a rotation.
+// https://en.wikipedia.org/wiki/Quaternions_and_spatial_rotation
+type quat struct {
+	x, y, z, w float64
+}
+
+// decompose splits a into the translation, rotation, and scale that make it up.
+func (a *Mtx) decompose() (shift Vec, rot quat, scale Vec) {
+	shift = Vec{a.el[3][0], a.el[3][1], a.el[3][2]}
+	var s [3]float64
+	var r [3][3]float64 // by row, then column
+	for c := 0; c < 3; c++ {
+		s[c] = math.Sqrt(a.el[c][0]*a.el[c][0] + a.el[c][1]*a.el[c][1] + a.el[c][2]*a.el[c][2])
+	}
+	x := Vec{a.el[0][0], a.el[0][1], a.el[0][2]}
+	y := Vec{a.el[1][0], a.el[1][1], a.el[1][2]}
+	z := Vec{a.el[2][0], a.el[2][1], a.el[2][2]}
+	if x.Dot(y.Cross(z)) < 0 {
+		s[0] = -s[0] // a mirrors space, which a rotation can't
+	}
+	for c := 0; c < 3; c++ {
+		for row := 0; row < 3; row++ {
+			if s[c] != 0 {
+				r[row][c] = a.el[c][row] / s[c]
+			}
+		}
+	}
+	return shift, rotation(r), Vec{s[0], s[1], s[2]}
+}
+
+// rotation returns the quaternion of a rotation matrix.
+// http://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
+func rotation(m [3][3]float64) quat {
+	switch tr := m[0][0] + m[1][1] + m[2][2]; {
+	case tr > 0:
+		s := math.Sqrt(tr+1) * 2
+		return quat{(m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s, s / 4}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := math.Sqrt(1+m[0][0]-m[1][1]-m[2][2]) * 2
+		return quat{s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s, (m[2][1] - m[1][2]) / s}
+	case m[1][1] > m[2][2]:
+		s := math.Sqrt(1+m[1][1]-m[0][0]-m[2][2]) * 2
+		return quat{(m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s, (m[0][2] - m[2][0]) / s}
+	default:
+		s := math.Sqrt(1+m[2][2]-m[0][0]-m[1][1]) * 2
+		return quat{(m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4, (m[1][0] - m[0][1]) / s}
+	}
+}
+
+// matrix returns the rotation matrix of q, by row, then column.
+func (q quat) matrix() [3][3]float64 {
+	x, y, z, w := q.x, q.y, q.z, q.w
+	return [3][3]float64{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// slerp returns the rotation t of the way along the shortest arc from a to b.
+// https://en.wikipedia.org/wiki/Slerp
+func slerp(a, b quat, t float64) quat {
+	dot := a.x*b.x + a.y*b.y + a.z*b.z + a.w*b.w
+	if dot < 0 {
+		b, dot = quat{-b.x, -b.y, -b.z, -b.w}, -dot
+	}
+	wa, wb := 1-t, t
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		wa = math.Sin((1-t)*theta) / math.Sin(theta)
+		wb = math.Sin(t*theta) / math.Sin(theta)
+	}
+	q := quat{wa*a.x + wb*b.x, wa*a.y + wb*b.y, wa*a.z + wb*b.z, wa*a.w + wb*b.w}
+	n := math.Sqrt(q.x*q.x + q.y*q.y + q.z*q.z + q.w*q.w)
+	return quat{q.x / n, q.y / n, q.z / n, q.w / n}
+}
@@ -11,7 +11,7 @@ var yAxis = Dir{0, 1, 0}
 // https://fgiesen.wordpress.com/2012/02/12/row-major-vs-column-major-row-vectors-vs-column-vectors/
 type Mtx struct {
 	el  [4][4]float64
-	inv *Mtx
+	inv *Mtx // cached by Inverse
 }
 
 // NewMat constructs a new matrix
@@ -156,10 +156,18 @@ func (a *Mtx) MultDir(v Dir) (result Dir) {
 // MultRay multiplies this matrix by a ray
 // https://gamedev.stackexchange.com/questions/72440/the-correct-way-to-transform-a-ray-with-a-matrix
 func (a *Mtx) MultRay(r *Ray) *Ray {
-	return NewRay(a.MultPoint(r.Origin), a.MultDir(r.Dir))
+	return NewRayAt(a.MultPoint(r.Origin), a.MultDir(r.Dir), r.Time)
 }
 
-// Inverse returns the inverse of this matrix
+// Prepare caches the inverse of this matrix and returns it.
+// Matrices that are shared between goroutines must be prepared first,
+// since Inverse caches the inverse the first time it's called, which isn't safe to do concurrently.
+func (a *Mtx) Prepare() *Mtx {
+	a.Inverse()
+	return a
+}
+
+// Inverse returns the inverse of this matrix, which it caches, along with this matrix as the inverse's inverse.
 // https://www.gamedev.net/forums/topic/648190-algorithm-for-4x4-matrix-inverse/
 // https://stackoverflow.com/questions/1148309/inverting-a-4x4-matrix
 func (a *Mtx) Inverse() *Mtx {
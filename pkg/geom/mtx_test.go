@@ -9,3 +9,32 @@ func TestInverse(t *testing.T) {
 		t.Error("Identity Inverse() should be Identity.")
 	}
 }
+
+func TestInterpolate(t *testing.T) {
+	axis := Vec{1, 2, -1}.Scaled(1 / Vec{1, 2, -1}.Len())
+	at := func(shift Vec, angle float64, scale Vec) *Mtx {
+		return Shift(shift).Mult(Rotate(axis.Scaled(angle))).Mult(Scale(scale))
+	}
+	a := at(Vec{1, 2, 3}, 0.3, Vec{1, 2, 3})
+	b := at(Vec{-1, 0, 5}, 2.5, Vec{3, 2, 1})
+	tests := []struct {
+		t    float64
+		want *Mtx
+	}{
+		{0, a},
+		{1, b},
+		{0.5, at(Vec{0, 1, 4}, 1.4, Vec{2, 2, 2})},
+		{0.25, at(Vec{0.5, 1.5, 3.5}, 0.85, Vec{1.5, 2, 2.5})},
+	}
+	for _, test := range tests {
+		got := Interpolate(a, b, test.t)
+		for _, p := range []Vec{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-2, 3, 1}} {
+			if d := got.MultPoint(p).Minus(test.want.MultPoint(p)).Len(); d > 1e-9 {
+				t.Errorf("at %v, %v moved to %v, expected %v", test.t, p, got.MultPoint(p), test.want.MultPoint(p))
+			}
+			if back := got.Inverse().MultPoint(got.MultPoint(p)); back.Minus(p).Len() > 1e-9 {
+				t.Errorf("at %v, %v moved back to %v", test.t, p, back)
+			}
+		}
+	}
+}
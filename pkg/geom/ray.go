@@ -1,18 +1,27 @@
 package geom
 
 // Ray describes a 3-dimensional ray with an origin and a unit direction Vector3.
+// Time is when the ray is cast, from 0 at the start of a frame to 1 at its end,
+// which places surfaces that move during the frame.
 type Ray struct {
 	Origin   Vec
 	Dir      Dir
+	Time     float64
 	OrArray  [3]float64
 	DirArray [3]float64
 	InvArray [3]float64
 }
 
 func NewRay(origin Vec, dir Dir) *Ray {
+	return NewRayAt(origin, dir, 0)
+}
+
+// NewRayAt returns a ray cast at a given time, such as a ray that continues a path at the time it was started.
+func NewRayAt(origin Vec, dir Dir, time float64) *Ray {
 	return &Ray{
 		Origin:   origin,
 		Dir:      dir,
+		Time:     time,
 		OrArray:  origin.Array(),
 		DirArray: Vec(dir).Array(),
 		InvArray: [3]float64{1 / dir.X, 1 / dir.Y, 1 / dir.Z},
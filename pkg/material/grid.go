@@ -27,8 +27,8 @@ func NewGrid(base, line surface.Material, tiles int, thickness float64) *Grid {
 }
 
 func (g *Grid) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
-	du := math.Mod(u, g.spacing)
-	dv := math.Mod(v, g.spacing)
+	du := u - g.spacing*math.Floor(u/g.spacing) // unlike math.Mod, positive for negative u (on Planes)
+	dv := v - g.spacing*math.Floor(v/g.spacing)
 	if du < g.radius || dv < g.radius {
 		return g.line.At(u, v, in, norm, rnd)
 	}
@@ -42,3 +42,7 @@ func (g *Grid) Light() rgb.Energy {
 func (g *Grid) Transmit() rgb.Energy {
 	return rgb.Black
 }
+
+func (g *Grid) Medium() *render.Medium {
+	return nil
+}
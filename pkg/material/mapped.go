@@ -3,6 +3,7 @@ package material
 import (
 	"image"
 	"image/color"
+	"math"
 	"math/rand"
 
 	"github.com/hunterloftis/pbr2/pkg/geom"
@@ -15,12 +16,19 @@ type Mapped struct {
 	Metalness image.Image
 	Roughness image.Image
 	Normal    image.Image
+	Bump      image.Image // heights, brighter is higher, that tilt the normal
+	Displace  image.Image // heights that meshes are displaced by before they're rendered
 	Base      *Uniform
+
+	BumpScale     float64 // height of white in Bump, in texels
+	DisplaceScale float64 // height of white in Displace, in the units of the mesh
 }
 
 func NewMapped(base *Uniform) *Mapped {
 	m := Mapped{
-		Base: base,
+		Base:          base,
+		BumpScale:     1,
+		DisplaceScale: 1,
 	}
 	return &m
 }
@@ -35,42 +43,94 @@ func colToFloat(c color.Color) float64 {
 	return float64(r+g+b) / (65535 * 3) // TODO: Need to take the length / square root here?
 }
 
-func (m *Mapped) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
-	sample := *m.Base
-	img := image.Image(nil)
-	x := 0
-	y := 0
-	if m.Color != nil {
-		img = m.Color
-	} else if m.Roughness != nil {
-		img = m.Roughness
+// texel returns the color of img at texture coordinates u, v, which wrap around its edges.
+func texel(img image.Image, u, v float64) color.Color {
+	w := img.Bounds().Max.X
+	h := img.Bounds().Max.Y
+	return pixel(img, int(u*float64(w)), int(-v*float64(h)))
+}
+
+// pixel returns the color of img at x, y, which wrap around its edges.
+func pixel(img image.Image, x, y int) color.Color {
+	w := img.Bounds().Max.X
+	h := img.Bounds().Max.Y
+	x %= w
+	if x < 0 {
+		x += w
 	}
-	if img != nil {
-		w := img.Bounds().Max.X
-		h := img.Bounds().Max.Y
-		u2 := u * float64(w)
-		v2 := -v * float64(h)
-		x = int(u2) % w
-		if x < 0 {
-			x += w
-		}
-		y = int(v2) % h
-		if y < 0 {
-			y += h
-		}
+	y %= h
+	if y < 0 {
+		y += h
 	}
+	return img.At(x, y)
+}
+
+// height returns the brightness of img at texture coordinates u, v,
+// blended between the four nearest texels so that it changes smoothly.
+func height(img image.Image, u, v float64) float64 {
+	x := u*float64(img.Bounds().Max.X) - 0.5
+	y := -v*float64(img.Bounds().Max.Y) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	i, j := int(x0), int(y0)
+	top := colToFloat(pixel(img, i, j))*(1-fx) + colToFloat(pixel(img, i+1, j))*fx
+	bottom := colToFloat(pixel(img, i, j+1))*(1-fx) + colToFloat(pixel(img, i+1, j+1))*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// colToNormal decodes a tangent-space normal from a normal map, whose red, green, and blue
+// channels hold the normal's components along u, v, and the surface normal, mapped from [-1, 1] to [0, 1].
+func colToNormal(c color.Color) geom.Dir {
+	e := colToEnergy(c)
+	n, ok := geom.Vec{e.X*2 - 1, e.Z*2 - 1, e.Y*2 - 1}.Unit()
+	if !ok || n.Y <= 0 {
+		return geom.Up
+	}
+	return n
+}
+
+func (m *Mapped) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	sample := *m.Base
 	if m.Color != nil {
-		sample.Color = colToEnergy(m.Color.At(x, y))
+		sample.Color = colToEnergy(texel(m.Color, u, v))
 	}
 	// if m.Metalness != nil {
-	// 	sample.Metalness = colToFloat(m.Metalness.At(x, y))
+	// 	sample.Metalness = colToFloat(texel(m.Metalness, u, v))
 	// }
 	if m.Roughness != nil {
-		sample.Roughness = colToFloat(m.Roughness.At(x, y))
+		sample.Roughness = colToFloat(texel(m.Roughness, u, v))
 	}
 	_, bsdf = sample.At(u, v, in, norm, rnd)
-	// TODO: combine normal map at u,v with norm
-	return geom.Up, bsdf
+	normal = geom.Up
+	if m.Normal != nil {
+		normal = colToNormal(texel(m.Normal, u, v))
+	}
+	if m.Bump != nil {
+		normal = m.bump(normal, u, v)
+	}
+	return normal, bsdf
+}
+
+// bump tilts the tangent-space normal n away from the slope of the Bump map at u, v.
+// Slopes are added to n's, so bumps show up on top of a normal map.
+// https://blog.selfshadow.com/publications/blending-in-detail/
+func (m *Mapped) bump(n geom.Dir, u, v float64) geom.Dir {
+	du, dv := 1/float64(m.Bump.Bounds().Max.X), 1/float64(m.Bump.Bounds().Max.Y)
+	su := (height(m.Bump, u+du, v) - height(m.Bump, u-du, v)) / 2 * m.BumpScale
+	sv := (height(m.Bump, u, v+dv) - height(m.Bump, u, v-dv)) / 2 * m.BumpScale
+	bumped, ok := geom.Vec{n.X/n.Y - su, 1, n.Z/n.Y - sv}.Unit()
+	if !ok {
+		return n
+	}
+	return bumped
+}
+
+// Displacement returns how far the surface at u, v is pushed out along its normal by the Displace map.
+func (m *Mapped) Displacement(u, v float64) float64 {
+	if m.Displace == nil {
+		return 0
+	}
+	return height(m.Displace, u, v) * m.DisplaceScale
 }
 
 func (m *Mapped) Light() rgb.Energy {
@@ -80,3 +140,7 @@ func (m *Mapped) Light() rgb.Energy {
 func (m *Mapped) Transmit() rgb.Energy {
 	return m.Base.Transmit()
 }
+
+func (m *Mapped) Medium() *render.Medium {
+	return m.Base.Medium()
+}
@@ -0,0 +1,33 @@
+package material_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+func TestMappedBump(t *testing.T) {
+	ramp := image.NewGray(image.Rect(0, 0, 8, 8)) // higher towards +u
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			ramp.SetGray(x, y, color.Gray{uint8(x * 32)})
+		}
+	}
+	m := material.NewMapped(&material.Uniform{Color: rgb.White})
+	m.Bump = ramp
+	rnd := rand.New(rand.NewSource(1))
+	n, _ := m.At(0.5, -0.5, geom.Dir{0, -1, 0}, geom.Up, rnd)
+	if n.X >= 0 || math.Abs(n.Z) > 1e-9 || n.Y <= 0 {
+		t.Errorf("expected the normal to tilt away from +u, got %v", n)
+	}
+	m.Bump = image.NewGray(image.Rect(0, 0, 8, 8))
+	if n, _ := m.At(0.5, -0.5, geom.Dir{0, -1, 0}, geom.Up, rnd); n != geom.Up {
+		t.Errorf("expected a flat bump map to leave the normal up, got %v", n)
+	}
+}
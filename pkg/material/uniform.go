@@ -9,9 +9,6 @@ import (
 	"github.com/hunterloftis/pbr2/pkg/rgb"
 )
 
-const reflect = 1.0 / 2.0
-const refract = 1 - reflect
-
 type Uniform struct {
 	Color        rgb.Energy
 	Metalness    float64
@@ -19,6 +16,14 @@ type Uniform struct {
 	Specularity  float64 // TODO: consider renaming to "F0" or "Fresnel0"
 	Emission     float64
 	Transmission float64 // TODO: scale this non-linearly so a 0-1 range is more natural (since 0.0001% - 100% is a "normal" range)
+
+	Sheen      float64 // strength of the soft glow at the edges of cloth
+	Subsurface float64 // fraction of diffuse light that scatters under the surface, like skin or marble
+
+	Clearcoat          float64 // strength of a clear, glossy layer over the surface, like the lacquer on car paint
+	ClearcoatRoughness float64
+
+	Interior *render.Medium // that fills closed surfaces that transmit light, like milk in glass; integrators absorb light by it rather than by Transmission
 }
 
 func (un *Uniform) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
@@ -33,31 +38,22 @@ func (un *Uniform) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir
 			Multiplier: 1,
 		}
 	}
-	if rnd.Float64() <= un.Metalness {
-		return geom.Up, bsdf.Microfacet{
-			Specular:   un.Color,
-			Roughness:  un.Roughness,
-			Multiplier: 1,
-		}
-	}
-	// TODO: dynamic reflect/refract ratio based on material properties
-	if rnd.Float64() < reflect {
-		return geom.Up, bsdf.Microfacet{
-			Specular:   rgb.Energy{un.Specularity, un.Specularity, un.Specularity},
-			Roughness:  un.Roughness,
-			Multiplier: 1 / reflect,
-		}
-	}
+	transmit := 0.0
 	if un.Transmission > 0 {
-		return geom.Up, bsdf.Transmit{
-			Specular:   un.Specularity,
-			Roughness:  un.Roughness,
-			Multiplier: 1 / refract,
-		}
+		transmit = 1
 	}
-	return geom.Up, bsdf.Lambert{
-		Color:      un.Color,
-		Multiplier: 1 / refract,
+	return geom.Up, bsdf.Layered{
+		Color:     un.Color,
+		Metalness: un.Metalness,
+		Specular:  un.Specularity,
+		Roughness: un.Roughness,
+		Transmit:  transmit,
+
+		Sheen:      un.Sheen,
+		Subsurface: un.Subsurface,
+
+		Clearcoat:          un.Clearcoat,
+		ClearcoatRoughness: un.ClearcoatRoughness,
 	}
 }
 
@@ -68,3 +64,7 @@ func (un *Uniform) Light() rgb.Energy {
 func (un *Uniform) Transmit() rgb.Energy {
 	return un.Color.Scaled(un.Transmission)
 }
+
+func (un *Uniform) Medium() *render.Medium {
+	return un.Interior
+}
@@ -0,0 +1,49 @@
+package material
+
+import (
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/bsdf"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Volume is an invisible surface around a participating medium, like fog or smoke.
+// Light passes straight through it, into and out of the medium.
+type Volume struct {
+	Interior *render.Medium
+}
+
+// Fog returns a Volume of white fog that scatters light evenly, density times per unit distance.
+func Fog(density float64) *Volume {
+	return &Volume{&render.Medium{
+		Scatter: rgb.Energy{density, density, density},
+	}}
+}
+
+// Smoke returns a Volume of grey smoke that absorbs as much light as it scatters, mostly onwards.
+func Smoke(density float64) *Volume {
+	d := density / 2
+	return &Volume{&render.Medium{
+		Absorb:  rgb.Energy{d, d, d},
+		Scatter: rgb.Energy{d, d, d},
+		G:       0.6,
+	}}
+}
+
+func (v *Volume) At(u, w float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
+	return geom.Up, bsdf.Ignore{}
+}
+
+func (v *Volume) Light() rgb.Energy {
+	return rgb.Black
+}
+
+func (v *Volume) Transmit() rgb.Energy {
+	return rgb.Black
+}
+
+func (v *Volume) Medium() *render.Medium {
+	return v.Interior
+}
@@ -0,0 +1,354 @@
+package render
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// vertex is a point along a camera or light subpath.
+// fwd and rev are the densities (per unit area) of generating the vertex
+// from the previous vertex along its own subpath, and from the next vertex in the opposite direction.
+type vertex struct {
+	pt       geom.Vec
+	normal   geom.Dir
+	toTan    *geom.Mtx
+	wo       geom.Dir // tangent-space direction back towards the previous vertex
+	bsdf     BSDF
+	obj      Object
+	beta     rgb.Energy // throughput from the start of the subpath up to this vertex
+	media    [2]*Medium // innermost media on wo's side of the surface and across it
+	fwd, rev float64
+	delta    bool // scattered by a specular lobe
+	lit      bool // has lobes that aren't specular, so it can be connected to
+	light    bool // on an emitter
+}
+
+// Bidirectional is a bidirectional path tracer.
+// For each camera ray it builds a camera subpath and a light subpath, each up to Bounce segments long,
+// then connects every pair of their vertices and weights each connection with multiple importance sampling.
+// Connections straight to the camera (light tracing) aren't made, since Cameras only generate rays.
+// Lights that aren't Emitters, and the Environment, are only found by camera subpaths.
+// Both subpaths start outside of every medium, and media dim light along every segment, but paths never scatter inside them,
+// so light that a medium scatters is lost; that's only accurate for media that absorb light, and fog and smoke need a PathTracer.
+// Emitters are always chosen by their power, even when Scene.Lights is a LightTree,
+// so that every strategy that could build a path agrees on the chance of starting it from the same emitter.
+// TODO: correct for shading normals and non-symmetric refraction along light subpaths.
+// https://graphics.stanford.edu/courses/cs348b-03/papers/veach-chapter10.pdf
+// http://www.pbr-book.org/3ed-2018/Light_Transport_III_Bidirectional_Methods/Bidirectional_Path_Tracing.html
+type Bidirectional struct {
+	Bounce int
+}
+
+// bdpt holds the subpaths for one camera ray; they're pooled to avoid reallocating them for every ray.
+type bdpt struct {
+	scene *Scene
+	rnd   *rand.Rand
+	time  float64 // of the camera ray, which both subpaths share
+	cam   []vertex
+	light []vertex
+}
+
+var subpaths = sync.Pool{
+	New: func() interface{} { return &bdpt{} },
+}
+
+func (b *Bidirectional) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	t := subpaths.Get().(*bdpt)
+	defer subpaths.Put(t)
+	t.scene, t.rnd, t.time = scene, rnd, ray.Time
+
+	edges := b.Bounce + 1
+	cam, energy := t.cameraPath(ray, edges)
+	light := t.lightPath(edges - 2)
+	t.cam, t.light = cam, light
+	for tt := 2; tt <= len(cam); tt++ {
+		for s := 0; s <= len(light) && s+tt-1 <= edges; s++ {
+			energy = energy.Plus(t.connect(cam, light, s, tt))
+		}
+	}
+	return energy
+}
+
+// cameraPath follows ray from the camera for up to edges segments.
+// It also returns any light from the Environment where the path escapes the scene.
+func (t *bdpt) cameraPath(ray *geom.Ray, edges int) ([]vertex, rgb.Energy) {
+	path := append(t.cam[:0], vertex{pt: ray.Origin, beta: rgb.White})
+	return t.walk(path, ray, rgb.White, 0, edges, true)
+}
+
+// lightPath emits a ray from a point on a randomly chosen emitter and follows it for up to edges segments.
+// Emitters are two-sided, so rays leave either side of the surface with a cosine distribution.
+func (t *bdpt) lightPath(edges int) []vertex {
+	path := t.light[:0]
+	if edges < 0 {
+		return path
+	}
+	l, prob := t.scene.emitters.Choose(geom.Vec{}, t.rnd)
+	if l == nil || prob <= 0 {
+		return path
+	}
+	pt, normal, pdf := l.(Emitter).SampleArea(t.rnd)
+	if pdf <= 0 {
+		return path
+	}
+	y0 := vertex{
+		pt:     pt,
+		normal: normal,
+		obj:    l,
+		beta:   l.Light(),
+		fwd:    prob * pdf,
+		light:  true,
+	}
+	path = append(path, y0)
+	if edges < 1 {
+		return path
+	}
+	_, fromTan := geom.Tangent(normal)
+	local, _ := geom.Up.RandHemiCos(t.rnd)
+	if t.rnd.Float64() < 0.5 {
+		local.Y = -local.Y
+	}
+	dirPDF := math.Abs(local.Y) / (2 * math.Pi)
+	if dirPDF <= 0 {
+		return path
+	}
+	beta := y0.beta.Scaled(math.Abs(local.Y) / (y0.fwd * dirPDF))
+	path, _ = t.walk(path, geom.NewRayAt(pt, fromTan.MultDir(local), t.time), beta, dirPDF, edges, false)
+	return path
+}
+
+// walk extends path along ray, sampling each vertex's BSDF for the next direction, until the path is edges long.
+// beta is the throughput carried by ray, and pdf is the density (per steradian) with which it was chosen.
+// Camera subpaths end on emitters; light subpaths end before them.
+// Paths keep track of the media they pass into, like the PathTracer's.
+func (t *bdpt) walk(path []vertex, ray *geom.Ray, beta rgb.Energy, pdf float64, edges int, camera bool) ([]vertex, rgb.Energy) {
+	var media []*Medium // that the path is inside, innermost last
+	for len(path) <= edges {
+		obj, dist := t.scene.Surface.Intersect(ray, infinity)
+		if obj == nil {
+			if camera {
+				tr := innermost(media).transmittance(infinity)
+				return path, t.scene.Env.At(ray.Dir).Times(beta).Times(tr)
+			}
+			break
+		}
+		beta = beta.Times(innermost(media).transmittance(dist))
+		pt := ray.Moved(dist)
+		prev := len(path) - 1
+
+		if !obj.Light().Zero() {
+			if camera {
+				v := vertex{pt: pt, obj: obj, beta: beta, light: true}
+				if e, ok := obj.(Emitter); ok {
+					v.normal, _ = e.AreaPDF(pt)
+				}
+				v.fwd = toArea(pdf, path[prev].pt, &v)
+				path = append(path, v)
+			}
+			break
+		}
+
+		normal, bsdf := obj.At(pt, ray.Dir, t.rnd)
+		enters := ray.Dir.Enters(normal)
+		if !enters && obj.Medium() == nil { // which has already absorbed light on the way
+			beta = beta.Times(beers(dist, obj.Transmit()))
+		}
+		across := cross(media, obj.Medium(), enters)
+		toTan, fromTan := geom.Tangent(normal)
+		v := vertex{
+			pt:     pt,
+			normal: normal,
+			toTan:  toTan,
+			wo:     toTan.MultDir(ray.Dir.Inv()),
+			bsdf:   bsdf,
+			obj:    obj,
+			beta:   beta,
+			media:  [2]*Medium{innermost(media), innermost(across)},
+		}
+		v.fwd = toArea(pdf, path[prev].pt, &v)
+		wi, wiPDF, shadow := bsdf.Sample(v.wo, t.rnd)
+		v.delta, v.lit = !shadow, lit(bsdf, v.wo, shadow)
+		path = append(path, v)
+
+		if len(path) > edges || wiPDF <= 0 {
+			break
+		}
+		beta = beta.Times(bsdf.Eval(wi, v.wo).Scaled(1 / wiPDF).Limit(maxWeight)).RandomGain(t.rnd)
+		if beta.Zero() {
+			break
+		}
+		pdf = wiPDF
+		rev := bsdf.PDF(v.wo, wi)
+		if v.delta {
+			pdf, rev = 0, 0
+		}
+		path[prev].rev = toArea(rev, pt, &path[prev])
+		if wi.Y*v.wo.Y < 0 {
+			media = across
+		}
+		ray = geom.NewRayAt(pt, fromTan.MultDir(wi), ray.Time)
+	}
+	return path, rgb.Black
+}
+
+// connect estimates the light carried by paths made of the first s vertices of the light subpath
+// and the first tt vertices of the camera subpath, weighted against the other ways of building the same path.
+// With s == 0, the camera subpath must have found an emitter by itself;
+// with s == 1, a new point is sampled on an emitter, as in the unidirectional tracer.
+func (t *bdpt) connect(cam, light []vertex, s, tt int) rgb.Energy {
+	z := &cam[tt-1]
+	var y vertex
+	var energy rgb.Energy
+
+	switch {
+	case s == 0:
+		if !z.light {
+			return rgb.Black
+		}
+		energy = z.beta.Times(z.obj.Light())
+		if _, ok := z.obj.(Emitter); !ok {
+			return energy
+		}
+	case z.light || !z.lit:
+		return rgb.Black
+	case s == 1:
+		l, prob := t.scene.emitters.Choose(geom.Vec{}, t.rnd)
+		if l == nil || prob <= 0 {
+			return rgb.Black
+		}
+		pt, normal, pdf := l.(Emitter).SampleArea(t.rnd)
+		y = vertex{pt: pt, normal: normal, obj: l, beta: l.Light(), fwd: prob * pdf, light: true}
+		if y.fwd <= 0 {
+			return rgb.Black
+		}
+		dir, dist, ok := direction(z.pt, y.pt)
+		if !ok {
+			return rgb.Black
+		}
+		wi := z.toTan.MultDir(dir)
+		f := z.bsdf.Eval(wi, z.wo).Times(z.medium(wi).transmittance(dist))
+		cos := math.Abs(normal.Dot(dir))
+		energy = z.beta.Times(f).Times(y.beta).Scaled(cos / (dist * dist * y.fwd))
+		if energy.Zero() || !t.scene.visible(z.pt, y.pt, t.time) {
+			return rgb.Black
+		}
+	default:
+		y = light[s-1]
+		if !y.lit {
+			return rgb.Black
+		}
+		dir, dist, ok := direction(y.pt, z.pt)
+		if !ok {
+			return rgb.Black
+		}
+		fy := y.bsdf.Eval(y.toTan.MultDir(dir), y.wo)
+		wi := z.toTan.MultDir(dir.Inv())
+		fz := z.bsdf.Eval(wi, z.wo).Times(z.medium(wi).transmittance(dist))
+		energy = y.beta.Times(fy).Times(fz).Times(z.beta).Scaled(1 / (dist * dist))
+		if energy.Zero() || !t.scene.visible(y.pt, z.pt, t.time) {
+			return rgb.Black
+		}
+	}
+	return energy.Scaled(t.misWeight(cam, light, s, tt, &y))
+}
+
+// misWeight returns the power heuristic weight of the strategy that connects s light and tt camera vertices,
+// relative to every other strategy that could have built the same path.
+// Reverse densities at the connection are only known once the path is complete,
+// and the vertices at the connection scatter towards each other rather than by the lobes they sampled,
+// so they're set here and restored before returning.
+// y is the light vertex sampled by connect when s == 1.
+func (t *bdpt) misWeight(cam, light []vertex, s, tt int, y *vertex) float64 {
+	z, zPrev := &cam[tt-1], &cam[tt-2]
+	defer func(z0, z1 float64) { z.rev, zPrev.rev = z0, z1 }(z.rev, zPrev.rev)
+
+	if s == 0 {
+		normal, pdf := z.obj.(Emitter).AreaPDF(z.pt)
+		z.rev = t.scene.emitters.Prob(z.obj, geom.Vec{}) * pdf
+		if dir, _, ok := direction(z.pt, zPrev.pt); ok {
+			zPrev.rev = toArea(math.Abs(normal.Dot(dir))/(2*math.Pi), z.pt, zPrev)
+		}
+	} else {
+		if s > 1 {
+			y = &light[s-1]
+		}
+		defer func(y0 float64, d0, d1 bool) { y.rev, y.delta, z.delta = y0, d0, d1 }(y.rev, y.delta, z.delta)
+		y.delta, z.delta = false, false
+		toZ, _, _ := direction(y.pt, z.pt)
+		if s == 1 {
+			z.rev = toArea(math.Abs(y.normal.Dot(toZ))/(2*math.Pi), y.pt, z)
+		} else {
+			z.rev = toArea(y.bsdf.PDF(y.toTan.MultDir(toZ), y.wo), y.pt, z)
+		}
+		toY := z.toTan.MultDir(toZ.Inv())
+		zPrev.rev = toArea(z.bsdf.PDF(z.wo, toY), z.pt, zPrev)
+		y.rev = toArea(z.bsdf.PDF(toY, z.wo), z.pt, y)
+		if s > 1 {
+			yPrev := &light[s-2]
+			defer func(y1 float64) { yPrev.rev = y1 }(yPrev.rev)
+			yPrev.rev = toArea(y.bsdf.PDF(y.wo, y.toTan.MultDir(toZ)), y.pt, yPrev)
+		}
+	}
+
+	sum := 0.0
+	r := 1.0
+	for i := tt - 1; i > 1; i-- {
+		r *= ratio(cam[i].rev, cam[i].fwd)
+		if !cam[i].delta && !cam[i-1].delta {
+			sum += r
+		}
+	}
+	r = 1.0
+	for i := s - 1; i >= 0; i-- {
+		v := &light[i]
+		if i == 0 && s == 1 {
+			v = y
+		}
+		r *= ratio(v.rev, v.fwd)
+		if !v.delta && (i == 0 || !light[i-1].delta) {
+			sum += r
+		}
+	}
+	return 1 / (1 + sum)
+}
+
+// medium returns the medium that light arriving at v from the tangent-space direction wi travels through.
+func (v *vertex) medium(wi geom.Dir) *Medium {
+	if wi.Y*v.wo.Y < 0 {
+		return v.media[1]
+	}
+	return v.media[0]
+}
+
+// direction returns the direction and distance from a to b.
+func direction(a, b geom.Vec) (geom.Dir, float64, bool) {
+	v := b.Minus(a)
+	dir, ok := v.Unit()
+	return dir, v.Len(), ok
+}
+
+// toArea converts a density per steradian, for a direction chosen at from, into a density per unit area at v.
+func toArea(pdf float64, from geom.Vec, v *vertex) float64 {
+	dir, dist, ok := direction(from, v.pt)
+	if !ok {
+		return 0
+	}
+	return pdf * math.Abs(v.normal.Dot(dir)) / (dist * dist)
+}
+
+// ratio returns the squared ratio of two densities for the power heuristic,
+// treating zero densities (from specular vertices) as one.
+func ratio(rev, fwd float64) float64 {
+	if rev == 0 {
+		rev = 1
+	}
+	if fwd == 0 {
+		fwd = 1
+	}
+	r := rev / fwd
+	return r * r
+}
@@ -0,0 +1,130 @@
+package render_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hunterloftis/pbr2/pkg/bsdf"
+	"github.com/hunterloftis/pbr2/pkg/camera"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+type dark struct{}
+
+func (d dark) At(geom.Dir) rgb.Energy {
+	return rgb.Black
+}
+
+// matte is a purely Lambertian material, which reflects light the same way in both directions.
+type matte rgb.Energy
+
+func (m matte) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
+	return geom.Up, bsdf.Lambert{Color: rgb.Energy(m), Multiplier: 1}
+}
+
+func (m matte) Light() rgb.Energy {
+	return rgb.Black
+}
+
+func (m matte) Transmit() rgb.Energy {
+	return rgb.Black
+}
+
+func (m matte) Medium() *render.Medium {
+	return nil
+}
+
+// boxScene is a box on a floor, lit only by a small spherical lamp.
+func boxScene() *render.Scene {
+	floor := surface.UnitCube(matte{0.6, 0.6, 0.6}).Shift(geom.Vec{0, -0.5, 0}).Scale(geom.Vec{4, 1, 4})
+	box := surface.UnitCube(matte{0.8, 0.2, 0.2}).Rotate(geom.Vec{0, 0.6, 0}).Shift(geom.Vec{0.3, 0.25, 0}).Scale(geom.Vec{0.5, 0.5, 0.5})
+	lamp := surface.UnitSphere(material.Light(1000, 1000, 1000)).Shift(geom.Vec{-0.5, 1.2, 0.3}).Scale(geom.Vec{0.3, 0.3, 0.3})
+	cam := camera.NewSLR().MoveTo(geom.Vec{0, 1, -2.5}).LookAt(geom.Vec{0, 0.2, 0})
+	return render.NewScene(cam, surface.NewList(floor, box, lamp), dark{})
+}
+
+func TestBidirectional(t *testing.T) {
+	const w, h, block = 32, 24, 8
+	sample := func(integrator render.Integrator, passes int) *render.Sample {
+		scene := boxScene()
+		scene.Integrator = integrator
+		f := render.NewFrame(scene, w, h, 0, false, 3)
+		f.Limit(passes)
+		f.Start()
+		for f.Active() {
+			time.Sleep(time.Millisecond)
+		}
+		f.Stop()
+		s, _ := f.Sample()
+		return s
+	}
+	uni := sample(&render.PathTracer{Bounce: 6, Direct: true}, 400)
+	bi := sample(&render.Bidirectional{Bounce: 6}, 200)
+
+	total := [2]float64{}
+	for by := 0; by < h; by += block {
+		for bx := 0; bx < w; bx += block {
+			sum := [2]float64{}
+			for y := by; y < by+block; y++ {
+				for x := bx; x < bx+block; x++ {
+					u, _ := uni.At(x, y)
+					b, _ := bi.At(x, y)
+					sum[0] += u.Mean()
+					sum[1] += b.Mean()
+				}
+			}
+			total[0] += sum[0]
+			total[1] += sum[1]
+			a, b := sum[0]/(block*block), sum[1]/(block*block)
+			if math.Abs(a-b) > 0.05+0.05*math.Max(a, b) {
+				t.Errorf("block at %v,%v: unidirectional %.3f, bidirectional %.3f", bx, by, a, b)
+			}
+		}
+	}
+	if diff := math.Abs(total[0]-total[1]) / total[0]; diff > 0.02 {
+		t.Errorf("totals differ by %.1f%%: unidirectional %.1f, bidirectional %.1f", diff*100, total[0], total[1])
+	}
+}
+
+// TestBidirectionalLightTree checks that choosing lights with a LightTree,
+// whose chances depend on the point being lit, doesn't bias bidirectional paths.
+func TestBidirectionalLightTree(t *testing.T) {
+	const w, h = 32, 24
+	sample := func(integrator render.Integrator, passes int) *render.Sample {
+		scene := boxScene()
+		lamp := surface.UnitSphere(material.Light(9000, 9000, 9000)).Shift(geom.Vec{12, 3, 8}).Scale(geom.Vec{0.1, 0.1, 0.1})
+		scene.Surface = surface.NewList(scene.Surface, lamp)
+		scene.Lights = render.NewLightTree(scene.Surface.Lights()...)
+		scene.Integrator = integrator
+		f := render.NewFrame(scene, w, h, 0, false, 3)
+		f.Limit(passes)
+		f.Start()
+		for f.Active() {
+			time.Sleep(time.Millisecond)
+		}
+		f.Stop()
+		s, _ := f.Sample()
+		return s
+	}
+	uni := sample(&render.PathTracer{Bounce: 6, Direct: true}, 400)
+	bi := sample(&render.Bidirectional{Bounce: 6}, 200)
+
+	total := [2]float64{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			u, _ := uni.At(x, y)
+			b, _ := bi.At(x, y)
+			total[0] += u.Mean()
+			total[1] += b.Mean()
+		}
+	}
+	if diff := math.Abs(total[0]-total[1]) / total[0]; diff > 0.02 {
+		t.Errorf("totals differ by %.1f%%: unidirectional %.1f, bidirectional %.1f", diff*100, total[0], total[1])
+	}
+}
@@ -0,0 +1,136 @@
+package render
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Debugging integrators render a property of the first surface each camera ray hits,
+// scaled so that 1 is displayed as white.
+const white = 255
+
+// Direct renders only the light that reaches each surface straight from a light or the Environment,
+// after a single reflection, with multiple importance sampling of both the lights and the BSDF.
+type Direct struct{}
+
+func (d *Direct) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	obj, dist := scene.Surface.Intersect(ray, infinity)
+	if obj == nil {
+		return scene.Env.At(ray.Dir)
+	}
+	if l := obj.Light(); !l.Zero() {
+		return l
+	}
+	pt := ray.Moved(dist)
+	normal, bsdf := obj.At(pt, ray.Dir, rnd)
+	toTan, fromTan := geom.Tangent(normal)
+	wo := toTan.MultDir(ray.Dir.Inv())
+	wi, pdf, shadow := bsdf.Sample(wo, rnd)
+
+	energy := rgb.Black
+	if lit(bsdf, wo, shadow) {
+		energy = scene.shadow(pt, ray.Time, wo, bsdf, toTan, nil, rnd)
+	}
+	if pdf <= 0 {
+		return energy
+	}
+	dir := fromTan.MultDir(wi)
+	reflectance := bsdf.Eval(wi, wo).Scaled(1 / pdf).Limit(maxWeight)
+	next, dist := scene.Surface.Intersect(geom.NewRayAt(pt, dir, ray.Time), infinity)
+	if next == nil {
+		return energy.Plus(scene.Env.At(dir).Times(reflectance))
+	}
+	if l := next.Light(); !l.Zero() {
+		weight := 1.0
+		if shadow {
+			weight = powerHeuristic(pdf, scene.lightPDF(next, pt, dir, dist))
+		}
+		energy = energy.Plus(l.Times(reflectance).Scaled(weight))
+	}
+	return energy
+}
+
+// AO renders ambient occlusion: how much of the hemisphere above each surface is open,
+// weighted by the cosine of each direction.
+// Surfaces further than Distance don't occlude; a Distance of 0 uses a tenth of the scene's bounding radius.
+type AO struct {
+	Distance float64
+}
+
+func (a *AO) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	obj, dist := scene.Surface.Intersect(ray, infinity)
+	if obj == nil {
+		return rgb.White.Scaled(white)
+	}
+	pt := ray.Moved(dist)
+	normal, _ := obj.At(pt, ray.Dir, rnd)
+	if !ray.Dir.Enters(normal) {
+		normal = normal.Inv()
+	}
+	max := a.Distance
+	if max <= 0 {
+		max = scene.Surface.Bounds().Radius * 0.1
+	}
+	dir, _ := normal.RandHemiCos(rnd)
+	if o, _ := scene.Surface.Intersect(geom.NewRayAt(pt, dir, ray.Time), max); o != nil {
+		return rgb.Black
+	}
+	return rgb.White.Scaled(white)
+}
+
+// Normals renders surface normals, mapping each axis from [-1, 1] to [0, 1].
+type Normals struct{}
+
+func (n *Normals) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	obj, dist := scene.Surface.Intersect(ray, infinity)
+	if obj == nil {
+		return rgb.Black
+	}
+	normal, _ := obj.At(ray.Moved(dist), ray.Dir, rnd)
+	return rgb.Energy{normal.X + 1, normal.Y + 1, normal.Z + 1}.Scaled(white / 2)
+}
+
+// Albedo renders the fraction of light that surfaces reflect towards the camera.
+// Each sample estimates it from a single direction chosen by the surface's BSDF.
+// Lights are shown at their emitted color.
+type Albedo struct{}
+
+func (a *Albedo) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	obj, dist := scene.Surface.Intersect(ray, infinity)
+	if obj == nil {
+		return rgb.Black
+	}
+	if l := obj.Light(); !l.Zero() {
+		return l.Scaled(white / l.Max())
+	}
+	normal, bsdf := obj.At(ray.Moved(dist), ray.Dir, rnd)
+	toTan, _ := geom.Tangent(normal)
+	wo := toTan.MultDir(ray.Dir.Inv())
+	wi, pdf, _ := bsdf.Sample(wo, rnd)
+	if pdf <= 0 {
+		return rgb.Black
+	}
+	return bsdf.Eval(wi, wo).Scaled(1 / pdf).Limit(maxWeight).Scaled(white)
+}
+
+// Depth renders the distance to surfaces, from white at the camera to black at Max.
+// A Max of 0 uses the distance to the far side of the scene's bounding sphere.
+type Depth struct {
+	Max float64
+}
+
+func (d *Depth) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	obj, dist := scene.Surface.Intersect(ray, infinity)
+	if obj == nil {
+		return rgb.Black
+	}
+	max := d.Max
+	if max <= 0 {
+		b := scene.Surface.Bounds()
+		max = b.Center.Minus(ray.Origin).Len() + b.Radius
+	}
+	return rgb.White.Scaled(white * math.Max(0, 1-dist/max))
+}
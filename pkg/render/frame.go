@@ -1,38 +1,85 @@
 package render
 
 import (
+	"image"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Frame renders a Scene progressively.
+// Its tracers pull tiles from a shared queue, one pass over one tile at a time,
+// so every region of the image improves at the same rate.
+// Each pass over a tile draws its random numbers from the Frame's seed, the pass, and the tile,
+// so a given seed and number of passes always produces the same Sample.
 type Frame struct {
 	scene   *Scene
-	data    *Sample
+	width   int
+	height  int
+	seed    int64
+	tiles   []*tile
 	workers []*tracer
-	in      chan *Sample
+	next    uint64
 	active  toggle
-	samples int
+	running sync.WaitGroup
+	snap    sync.RWMutex // held for reading while merging into tiles, for writing while taking snapshots
+
+	mu      sync.Mutex
+	changed *sync.Cond // broadcast when a tile merges a pass, when the Frame halts, and when its limits change
+	version int        // of the Frame's progress and limits, counting changes
 }
 
-func NewFrame(s *Scene, width, height, bounce int, direct bool) *Frame {
-	workers := runtime.NumCPU()
-	f := Frame{
-		scene:   s,
-		data:    NewSample(width, height),
+// NewFrame creates a Frame that renders Scene s.
+// Unless s has its own Integrator, it's rendered by a PathTracer with the given bounce and direct settings.
+// A seed of 0 picks a seed based on the current time.
+func NewFrame(s *Scene, width, height, bounce int, direct bool, seed int64) *Frame {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	workers := runtime.GOMAXPROCS(0)
+	f := &Frame{
+		scene:   s.prepare(bounce, direct),
+		width:   width,
+		height:  height,
+		seed:    seed,
+		tiles:   newTiles(width, height),
 		workers: make([]*tracer, workers),
-		in:      make(chan *Sample, workers*2),
 	}
+	f.changed = sync.NewCond(&f.mu)
 	for w := 0; w < workers; w++ {
-		f.workers[w] = newTracer(f.scene, f.in, width, height, bounce, direct)
+		f.workers[w] = newTracer(f)
+	}
+	return f
+}
+
+// Adapt enables adaptive sampling.
+// Pixels whose relative error (see Sample.Error) falls to target stop receiving new samples,
+// and the Frame stops itself once every pixel has converged.
+// A target of 0 samples every pixel on every pass.
+func (f *Frame) Adapt(target float64) {
+	for _, t := range f.tiles {
+		t.adapt(target)
 	}
-	go f.process()
-	return &f
+	f.change()
+}
+
+// Limit stops the Frame by itself once it has completed the given number of passes.
+// A limit of 0 renders until Stop is called.
+func (f *Frame) Limit(passes int) {
+	for _, t := range f.tiles {
+		t.setLimit(passes)
+	}
+	f.change()
 }
 
 func (f *Frame) Clear() {
-	f.active.mu.Lock()
-	defer f.active.mu.Unlock()
-	f.data = NewSample(f.data.Width, f.data.Height)
-	f.samples = 0
+	f.snap.Lock()
+	defer f.snap.Unlock()
+	for _, t := range f.tiles {
+		t.clear()
+	}
+	f.change()
 }
 
 func (f *Frame) Active() bool {
@@ -47,33 +94,115 @@ func (f *Frame) Start() {
 	}
 }
 
+// Stop stops the Frame and waits for passes that are in progress to be merged.
 func (f *Frame) Stop() {
+	f.halt()
+	f.running.Wait()
+}
+
+// Sample returns a consistent copy of the samples collected so far and the number of completed passes.
+// The copy is never modified by the Frame.
+func (f *Frame) Sample() (*Sample, int) {
+	f.snap.Lock()
+	defer f.snap.Unlock()
+	s := NewSample(f.width, f.height)
+	n := 0
+	for i, t := range f.tiles {
+		if p := t.copyTo(s); i == 0 || p < n {
+			n = p
+		}
+	}
+	return s, n
+}
+
+// Image returns a consistent image of the samples collected so far and the number of completed passes.
+// It is cheaper than Sample().Image() because it skips copying the raw samples.
+func (f *Frame) Image() (*image.RGBA, int) {
+	f.snap.Lock()
+	defer f.snap.Unlock()
+	im := image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+	n := 0
+	for i, t := range f.tiles {
+		if p := t.drawTo(im); i == 0 || p < n {
+			n = p
+		}
+	}
+	return im, n
+}
+
+// Samples returns the number of passes that have been completed over the whole Frame.
+func (f *Frame) Samples() int {
+	n := 0
+	for i, t := range f.tiles {
+		if p := t.count(); i == 0 || p < n {
+			n = p
+		}
+	}
+	return n
+}
+
+// halt stops the tracers without waiting for them; tracers call it on their own Frame.
+func (f *Frame) halt() {
 	if f.active.Set(false) {
 		for _, w := range f.workers {
 			w.stop()
 		}
+		f.change()
 	}
 }
 
-// TODO: this locking is pointless; still reading from *Sample
-// after returning. Should return copy of Sample instead.
-func (f *Frame) Sample() (*Sample, int) {
-	f.active.mu.Lock()
-	defer f.active.mu.Unlock()
-	return f.data, f.samples
+// merge adds a pass to a tile without interleaving with a snapshot.
+func (f *Frame) merge(t *tile, s *Sample) bool {
+	f.snap.RLock()
+	defer f.snap.RUnlock()
+	defer f.change()
+	return t.merge(s)
 }
 
-func (f *Frame) Samples() int {
-	f.active.mu.RLock()
-	defer f.active.mu.RUnlock()
-	return f.samples
+// change wakes tracers that are waiting for the Frame to change.
+func (f *Frame) change() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	f.changed.Broadcast()
+}
+
+// progress returns the version of the Frame, to wait for a change since.
+func (f *Frame) progress() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.version
 }
 
-func (f *Frame) process() {
-	for s := range f.in {
-		f.active.mu.Lock()
-		f.data.Merge(s)
-		f.samples++
-		f.active.mu.Unlock()
+// await blocks until the Frame has changed since version, or has halted.
+func (f *Frame) await(version int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.version == version && f.active.State() {
+		f.changed.Wait()
+	}
+}
+
+// job returns the next pass over a tile from the work queue.
+// The queue cycles through every tile before revisiting any of them.
+// It returns false when no tile has passes left to give.
+func (f *Frame) job() (t *tile, pass int, ok bool) {
+	for range f.tiles {
+		i := atomic.AddUint64(&f.next, 1) - 1
+		t = f.tiles[i%uint64(len(f.tiles))]
+		if pass, ok = t.issue(); ok {
+			return t, pass, true
+		}
+	}
+	return nil, 0, false
+}
+
+// finish halts the Frame if every tile has converged or completed its passes.
+func (f *Frame) finish() {
+	for _, t := range f.tiles {
+		if !t.finished() {
+			return
+		}
 	}
+	f.halt()
 }
@@ -0,0 +1,145 @@
+package render_test
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hunterloftis/pbr2/pkg/camera"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+type sky struct{}
+
+func (s sky) At(geom.Dir) rgb.Energy {
+	return rgb.Energy{500, 500, 500}
+}
+
+func testScene() *render.Scene {
+	floor := surface.UnitCube(material.Plastic(1, 1, 1, 0.2))
+	floor.Shift(geom.Vec{0, -0.1, 0}).Scale(geom.Vec{10, 0.1, 10})
+	ball := surface.UnitSphere(material.Gold(0.1, 1))
+	ball.Scale(geom.Vec{0.1, 0.1, 0.1})
+	cam := camera.NewSLR().MoveTo(geom.Vec{0, 0, -0.5}).LookAt(geom.Vec{0, 0, 0})
+	return render.NewScene(cam, surface.NewList(ball, floor), sky{})
+}
+
+// Run with -race to check that snapshots never share memory with the tracers.
+func TestFrameSnapshot(t *testing.T) {
+	f := render.NewFrame(testScene(), 70, 40, 3, true, 0)
+	f.Start()
+	defer f.Stop()
+
+	done := time.After(500 * time.Millisecond)
+	prev := 0
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		s, n := f.Sample()
+		if n < prev {
+			t.Fatalf("completed passes decreased from %v to %v", prev, n)
+		}
+		prev = n
+		for y := 0; y < s.Height; y++ {
+			for x := 0; x < s.Width; x++ {
+				if _, c := s.At(x, y); c < n {
+					t.Fatalf("pixel %v,%v has %v samples after %v passes", x, y, c, n)
+				}
+			}
+		}
+		total := s.Total()
+		im, _ := f.Image()
+		if b := im.Bounds(); b.Dx() != 70 || b.Dy() != 40 {
+			t.Fatalf("expected a 70x40 image, got %v", b)
+		}
+		if s.Total() != total {
+			t.Fatal("sample changed after it was returned")
+		}
+	}
+}
+
+func TestFrameSeed(t *testing.T) {
+	sample := func(procs int) []byte {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		f := render.NewFrame(testScene(), 70, 40, 3, true, 7)
+		f.Limit(4)
+		f.Start()
+		for f.Active() {
+			time.Sleep(time.Millisecond)
+		}
+		f.Stop()
+		s, n := f.Sample()
+		if n != 4 {
+			t.Fatalf("expected 4 passes, got %v", n)
+		}
+		buf, err := s.Buffer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	if !bytes.Equal(sample(1), sample(4)) {
+		t.Error("renders with the same seed should be identical")
+	}
+}
+
+// TestFrameAdapt checks that a scene of one flat color converges as soon as its error can be estimated,
+// and that the Frame stops itself once it has.
+func TestFrameAdapt(t *testing.T) {
+	cam := camera.NewSLR()
+	f := render.NewFrame(render.NewScene(cam, surface.NewList(), sky{}), 40, 40, 3, true, 1)
+	f.Adapt(0.01)
+	f.Start()
+	timeout := time.After(10 * time.Second)
+	for f.Active() {
+		select {
+		case <-timeout:
+			f.Stop()
+			t.Fatal("the frame didn't stop after converging")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	f.Stop()
+	s, _ := f.Sample()
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			if e := s.Error(x, y); e > 0.01 {
+				t.Fatalf("pixel %v,%v stopped with an error of %v", x, y, e)
+			}
+			if _, n := s.At(x, y); n != 16 {
+				t.Fatalf("pixel %v,%v has %v samples, expected 16", x, y, n)
+			}
+		}
+	}
+}
+
+// TestFrameAdaptPixels checks that pixels of the sky stop receiving samples once they've converged,
+// while noisy pixels of the floor below it keep receiving them.
+func TestFrameAdaptPixels(t *testing.T) {
+	const w, h, passes = 70, 40, 40
+	f := render.NewFrame(testScene(), w, h, 3, true, 1)
+	f.Adapt(0.0001)
+	f.Limit(passes)
+	f.Start()
+	for f.Active() {
+		time.Sleep(time.Millisecond)
+	}
+	f.Stop()
+	s, _ := f.Sample()
+	for x := 0; x < w; x++ {
+		if _, n := s.At(x, 0); n != 16 {
+			t.Errorf("sky pixel %v,0 has %v samples, expected 16", x, n)
+		}
+		if _, n := s.At(x, h-1); n != passes {
+			t.Errorf("floor pixel %v,%v has %v samples, expected %v", x, h-1, n, passes)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Integrator estimates the light arriving at the camera along a ray.
+// Frames call Li from many goroutines at once, each with its own rnd,
+// and pass a copy of their Scene with Lights filled in.
+type Integrator interface {
+	Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy
+}
+
+// Integrators lists the names accepted by NewIntegrator.
+var Integrators = []string{"path", "bidirectional", "direct", "ao", "normals", "albedo", "depth"}
+
+// NewIntegrator returns the Integrator with the given name.
+// Path tracers follow paths for up to bounce segments, and the unidirectional one only samples lights when direct is set.
+func NewIntegrator(name string, bounce int, direct bool) (Integrator, error) {
+	switch name {
+	case "", "path":
+		return &PathTracer{Bounce: bounce, Direct: direct}, nil
+	case "bidirectional", "bdpt":
+		return &Bidirectional{Bounce: bounce}, nil
+	case "direct":
+		return &Direct{}, nil
+	case "ao":
+		return &AO{}, nil
+	case "normals":
+		return &Normals{}, nil
+	case "albedo":
+		return &Albedo{}, nil
+	case "depth":
+		return &Depth{}, nil
+	}
+	return nil, fmt.Errorf("unknown integrator %q (expected one of %v)", name, Integrators)
+}
@@ -14,14 +14,43 @@ import (
 	"golang.org/x/text/message"
 )
 
-func Iterative(scene *Scene, file string, width, height, depth int, direct bool) error {
+// Options configures an Iterative render.
+// Frames, Time, and Error describe when the render should end on its own;
+// zero values mean no limit, and the render ends when any limit is reached.
+// A non-zero Error also enables adaptive sampling (see Frame.Adapt).
+type Options struct {
+	Frames float64 // number of full-frame passes
+	Time   float64 // seconds of wall-clock time
+	Error  float64 // relative error that every pixel must reach
+	Seed   int64   // random seed (see NewFrame)
+}
+
+func (o Options) reached(frames int, secs float64) bool {
+	if o.Frames > 0 && float64(frames) >= o.Frames {
+		return true
+	}
+	if o.Time > 0 && secs >= o.Time {
+		return true
+	}
+	return false
+}
+
+func Iterative(scene *Scene, file string, width, height, depth int, direct bool, opts Options) error {
 	kill := make(chan os.Signal, 2)
 	signal.Notify(kill, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(kill)
 
-	frame := scene.Render(width, height, depth, direct)
+	frame := NewFrame(scene, width, height, depth, direct, opts.Seed)
+	frame.Adapt(opts.Error)
+	if opts.Frames > 0 && !math.IsInf(opts.Frames, 1) {
+		frame.Limit(int(math.Ceil(opts.Frames)))
+	}
+	frame.Start()
 	defer frame.Stop()
 	ticker := time.NewTicker(6 * time.Second) // 10 .s = 1 minute, 100 .s = 1 hr
 	defer ticker.Stop()
+	check := time.NewTicker(100 * time.Millisecond)
+	defer check.Stop()
 
 	start := time.Now().UnixNano()
 	max := 0
@@ -31,19 +60,28 @@ func Iterative(scene *Scene, file string, width, height, depth int, direct bool)
 		select {
 		case <-kill:
 			frame.Stop()
+		case <-check.C:
+			secs := float64(time.Now().UnixNano()-start) / 1e9
+			if opts.reached(frame.Samples(), secs) {
+				frame.Stop()
+			}
 		case <-ticker.C:
-			if sample, n := frame.Sample(); n > max {
+			if im, n := frame.Image(); n > max {
 				max = n
 				fmt.Print(".")
-				if err := writePng(file, sample.Image()); err != nil {
+				if err := writePng(file, im); err != nil {
 					return err
 				}
 			}
 		}
 	}
 
+	frame.Stop()
 	stop := time.Now().UnixNano()
 	sample, _ := frame.Sample()
+	if err := writePng(file, sample.Image()); err != nil {
+		return err
+	}
 	total := sample.Total()
 	p := message.NewPrinter(language.English)
 	secs := float64(stop-start) / 1e9
@@ -0,0 +1,221 @@
+package render
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+)
+
+// LightSampler chooses which light to sample for direct lighting at a point.
+// Choose returns a light along with the probability that it was chosen,
+// and Prob returns the probability that Choose would pick light from pt.
+type LightSampler interface {
+	Choose(pt geom.Vec, rnd *rand.Rand) (light Object, prob float64)
+	Prob(light Object, pt geom.Vec) float64
+}
+
+// Emitter is implemented by lights that can be sampled by surface area.
+// SampleArea returns a point on the surface, its normal, and its density per unit area,
+// and AreaPDF returns the normal and density with which SampleArea would choose pt.
+// Lights that don't implement it are sampled through their bounding spheres
+// and treated as if their bounding boxes emitted light.
+type Emitter interface {
+	Area() float64
+	SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64)
+	AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64)
+}
+
+// Emitters returns the lights that are Emitters.
+func Emitters(lights []Object) []Object {
+	es := make([]Object, 0, len(lights))
+	for _, l := range lights {
+		if _, ok := l.(Emitter); ok {
+			es = append(es, l)
+		}
+	}
+	return es
+}
+
+// power estimates the total light emitted by a light.
+func power(l Object) float64 {
+	area := 0.0
+	if e, ok := l.(Emitter); ok {
+		area = e.Area()
+	} else {
+		area = l.Bounds().SurfaceArea()
+	}
+	return l.Light().Mean() * area
+}
+
+// PowerLights chooses lights in proportion to their emitted power,
+// so a small, dim light is rarely chosen over a large, bright one.
+type PowerLights struct {
+	lights []Object
+	cdf    []float64
+	prob   map[Object]float64
+}
+
+func NewPowerLights(lights ...Object) *PowerLights {
+	p := PowerLights{
+		lights: lights,
+		cdf:    make([]float64, len(lights)),
+		prob:   make(map[Object]float64, len(lights)),
+	}
+	total := 0.0
+	for i, l := range lights {
+		total += power(l)
+		p.cdf[i] = total
+	}
+	for i, l := range lights {
+		w := p.cdf[i]
+		if i > 0 {
+			w -= p.cdf[i-1]
+		}
+		if total > 0 {
+			p.prob[l] += w / total
+		} else {
+			p.prob[l] += 1 / float64(len(lights))
+		}
+	}
+	return &p
+}
+
+func (p *PowerLights) Choose(pt geom.Vec, rnd *rand.Rand) (Object, float64) {
+	if len(p.lights) == 0 {
+		return nil, 0
+	}
+	total := p.cdf[len(p.cdf)-1]
+	if total <= 0 {
+		l := p.lights[rnd.Intn(len(p.lights))]
+		return l, p.prob[l]
+	}
+	u := rnd.Float64() * total
+	i := sort.SearchFloat64s(p.cdf, u)
+	if i >= len(p.lights) {
+		i = len(p.lights) - 1
+	}
+	l := p.lights[i]
+	return l, p.prob[l]
+}
+
+func (p *PowerLights) Prob(light Object, pt geom.Vec) float64 {
+	return p.prob[light]
+}
+
+// LightTree is a bounding volume hierarchy of lights.
+// It chooses lights by descending the tree towards the clusters
+// that are most powerful relative to their squared distance from the point being lit.
+// https://dl.acm.org/citation.cfm?id=3233305
+type LightTree struct {
+	root   *lightNode
+	leaves map[Object]*lightNode
+}
+
+type lightNode struct {
+	bounds *geom.Bounds
+	power  float64
+	light  Object
+	parent *lightNode
+	left   *lightNode
+	right  *lightNode
+}
+
+func NewLightTree(lights ...Object) *LightTree {
+	t := LightTree{
+		leaves: make(map[Object]*lightNode, len(lights)),
+	}
+	if len(lights) > 0 {
+		ls := make([]Object, len(lights))
+		copy(ls, lights)
+		t.root = t.build(ls, nil)
+	}
+	return &t
+}
+
+func (t *LightTree) build(lights []Object, parent *lightNode) *lightNode {
+	n := lightNode{
+		bounds: lights[0].Bounds(),
+		parent: parent,
+	}
+	for _, l := range lights {
+		n.bounds = geom.MergeBounds(n.bounds, l.Bounds())
+		n.power += power(l)
+	}
+	if len(lights) == 1 {
+		n.light = lights[0]
+		t.leaves[n.light] = &n
+		return &n
+	}
+	axis := 0
+	size := n.bounds.Max.Minus(n.bounds.Min)
+	for a := 1; a < 3; a++ {
+		if size.Axis(a) > size.Axis(axis) {
+			axis = a
+		}
+	}
+	sort.Slice(lights, func(i, j int) bool {
+		return lights[i].Bounds().Center.Axis(axis) < lights[j].Bounds().Center.Axis(axis)
+	})
+	mid := len(lights) / 2
+	n.left = t.build(lights[:mid], &n)
+	n.right = t.build(lights[mid:], &n)
+	return &n
+}
+
+func (t *LightTree) Choose(pt geom.Vec, rnd *rand.Rand) (Object, float64) {
+	if t.root == nil {
+		return nil, 0
+	}
+	n := t.root
+	prob := 1.0
+	for n.light == nil {
+		p := n.leftProb(pt)
+		if rnd.Float64() < p {
+			n = n.left
+			prob *= p
+		} else {
+			n = n.right
+			prob *= 1 - p
+		}
+	}
+	return n.light, prob
+}
+
+func (t *LightTree) Prob(light Object, pt geom.Vec) float64 {
+	n := t.leaves[light]
+	if n == nil {
+		return 0
+	}
+	prob := 1.0
+	for ; n.parent != nil; n = n.parent {
+		p := n.parent.leftProb(pt)
+		if n == n.parent.left {
+			prob *= p
+		} else {
+			prob *= 1 - p
+		}
+	}
+	return prob
+}
+
+// leftProb returns the probability of descending to the left child when lighting pt.
+func (n *lightNode) leftProb(pt geom.Vec) float64 {
+	l := n.left.importance(pt)
+	r := n.right.importance(pt)
+	if l+r <= 0 {
+		return 0.5
+	}
+	return l / (l + r)
+}
+
+// importance approximates how much light the node sends towards pt.
+// Distances are never less than the node's radius, so nearby clusters don't overwhelm the estimate.
+func (n *lightNode) importance(pt geom.Vec) float64 {
+	dist := math.Max(n.bounds.Center.Minus(pt).Len(), n.bounds.Radius)
+	if dist <= 0 {
+		return n.power
+	}
+	return n.power / (dist * dist)
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Medium is a homogeneous participating medium that fills a closed surface, like fog, smoke, milk, or murky water.
+// Light travelling through it is absorbed, or scattered into a new direction by a Henyey-Greenstein phase function,
+// with a chance per unit distance of Absorb and Scatter.
+// https://www.pbr-book.org/3ed-2018/Volume_Scattering
+type Medium struct {
+	Absorb  rgb.Energy
+	Scatter rgb.Energy
+	G       float64 // asymmetry of scattering, from -1 (back the way light came) through 0 (evenly) to 1 (onwards)
+}
+
+// transmittance returns the fraction of light that passes dist through the medium without being absorbed or scattered.
+// Light passes through no medium (nil) unchanged.
+func (m *Medium) transmittance(dist float64) rgb.Energy {
+	if m == nil {
+		return rgb.White
+	}
+	return falloff(m.extinction(), dist)
+}
+
+// sample chooses how far light travels through the medium before it scatters, up to dist.
+// It returns the distance, whether the light scattered there or reached dist,
+// and the weight of the sample: the transmittance, and the scattering if it scattered, over the density.
+// Each sample chooses its distance by the extinction of a random channel, and weighs it against all of them.
+// https://www.pbr-book.org/3ed-2018/Light_Transport_II_Volume_Rendering/Sampling_Volume_Scattering
+func (m *Medium) sample(dist float64, rnd *rand.Rand) (t float64, scattered bool, weight rgb.Energy) {
+	ext := m.extinction()
+	sigma := [3]float64{ext.X, ext.Y, ext.Z}[rnd.Intn(3)]
+	t = infinity
+	if sigma > 0 {
+		t = -math.Log(1-rnd.Float64()) / sigma
+	}
+	if t < dist {
+		tr := falloff(ext, t)
+		pdf := tr.Times(ext).Mean()
+		if pdf <= 0 {
+			return t, true, rgb.Black
+		}
+		return t, true, tr.Times(m.Scatter).Scaled(1 / pdf)
+	}
+	tr := falloff(ext, dist)
+	pdf := tr.Mean()
+	if pdf <= 0 {
+		return dist, false, rgb.Black
+	}
+	return dist, false, tr.Scaled(1 / pdf)
+}
+
+func (m *Medium) extinction() rgb.Energy {
+	return m.Absorb.Plus(m.Scatter)
+}
+
+// phase returns the phase function of the medium as a BSDF.
+// It only depends on the angle between wi and wo, so it works in any space.
+func (m *Medium) phase() BSDF {
+	return henyeyGreenstein{g: m.G}
+}
+
+// henyeyGreenstein is a phase function that scatters light onwards when g > 0, and back when g < 0.
+// As a BSDF, light arrives from wi and leaves towards wo, and Eval has no cosine term.
+// http://www.astro.umd.edu/~jph/HG_note.pdf
+type henyeyGreenstein struct {
+	g float64
+}
+
+func (h henyeyGreenstein) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
+	g := h.g
+	cos := 1 - 2*rnd.Float64()
+	if math.Abs(g) > 1e-3 {
+		s := (1 - g*g) / (1 - g + 2*g*rnd.Float64())
+		cos = (1 + g*g - s*s) / (2 * g)
+	}
+	sin := math.Sqrt(math.Max(0, 1-cos*cos))
+	phi := 2 * math.Pi * rnd.Float64()
+	d := wo.Inv() // the way light was going
+	u, _ := d.Cross(geom.Dir{X: 1})
+	if math.Abs(d.X) > 0.9 {
+		u, _ = d.Cross(geom.Dir{Z: 1})
+	}
+	v, _ := d.Cross(u)
+	wi, ok := u.Scaled(sin * math.Cos(phi)).Plus(v.Scaled(sin * math.Sin(phi))).Plus(d.Scaled(cos)).Unit()
+	if !ok {
+		return wo, 0, true
+	}
+	return wi, h.PDF(wi, wo), true
+}
+
+func (h henyeyGreenstein) PDF(wi, wo geom.Dir) float64 {
+	g := h.g
+	cos := wo.Inv().Dot(wi)
+	d := 1 + g*g - 2*g*cos
+	return (1 - g*g) / (4 * math.Pi * d * math.Sqrt(d))
+}
+
+func (h henyeyGreenstein) Eval(wi, wo geom.Dir) rgb.Energy {
+	return rgb.White.Scaled(h.PDF(wi, wo))
+}
+
+// falloff returns the fraction of light that travels dist through a medium with extinction ext, by Beer's law.
+func falloff(ext rgb.Energy, dist float64) rgb.Energy {
+	return rgb.Energy{X: decay(ext.X, dist), Y: decay(ext.Y, dist), Z: decay(ext.Z, dist)}
+}
+
+func decay(ext, dist float64) float64 {
+	if ext == 0 {
+		return 1 // even over an infinite distance
+	}
+	return math.Exp(-ext * dist)
+}
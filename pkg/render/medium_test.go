@@ -0,0 +1,108 @@
+package render_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hunterloftis/pbr2/pkg/camera"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// smokeScene is a box and a lamp on a floor, in a cube of smoke.
+func smokeScene() *render.Scene {
+	floor := surface.UnitCube(matte{0.6, 0.6, 0.6}).Shift(geom.Vec{0, -0.5, 0}).Scale(geom.Vec{4, 1, 4})
+	box := surface.UnitCube(matte{0.8, 0.2, 0.2}).Rotate(geom.Vec{0, 0.6, 0}).Shift(geom.Vec{0.3, 0.25, 0}).Scale(geom.Vec{0.5, 0.5, 0.5})
+	lamp := surface.UnitSphere(material.Light(300, 300, 300)).Shift(geom.Vec{-0.5, 1.2, 0.3}).Scale(geom.Vec{0.6, 0.6, 0.6})
+	smoke := surface.UnitCube(material.Smoke(0.6)).Shift(geom.Vec{0, 0.3, 0}).Scale(geom.Vec{4.5, 4.5, 4.5})
+	cam := camera.NewSLR().MoveTo(geom.Vec{0, 1, -2.5}).LookAt(geom.Vec{0, 0.2, 0})
+	return render.NewScene(cam, surface.NewList(floor, box, lamp, smoke), dark{})
+}
+
+// TestMedium checks that a scene in a medium is as bright whether light is found
+// by sampling lights from where paths scatter and reflect or only by paths that hit them.
+// Paths that only hit lights are too noisy to compare parts of the image.
+func TestMedium(t *testing.T) {
+	const w, h = 32, 24
+	sample := func(direct bool, passes int) *render.Sample {
+		scene := smokeScene()
+		scene.Integrator = &render.PathTracer{Bounce: 8, Direct: direct}
+		f := render.NewFrame(scene, w, h, 0, false, 3)
+		f.Limit(passes)
+		f.Start()
+		for f.Active() {
+			time.Sleep(time.Millisecond)
+		}
+		f.Stop()
+		s, _ := f.Sample()
+		return s
+	}
+	direct := sample(true, 300)
+	hit := sample(false, 1500)
+
+	total := [2]float64{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d, _ := direct.At(x, y)
+			u, _ := hit.At(x, y)
+			total[0] += d.Mean()
+			total[1] += u.Mean()
+		}
+	}
+	if total[0] == 0 {
+		t.Fatal("the scene is black")
+	}
+	if diff := math.Abs(total[0]-total[1]) / total[0]; diff > 0.03 {
+		t.Errorf("totals differ by %.1f%%: sampling lights %.1f, hitting them %.1f", diff*100, total[0], total[1])
+	}
+}
+
+// TestMediumGlass checks that light through a glass sphere filled with an absorbing medium
+// is only absorbed by the medium, and not again by the glass's transmission, by both path tracers.
+func TestMediumGlass(t *testing.T) {
+	const w, h, absorb = 5, 5, 1.0
+	tests := []struct {
+		name       string
+		integrator render.Integrator
+	}{
+		{"unidirectional", &render.PathTracer{Bounce: 4, Direct: true}},
+		{"bidirectional", &render.Bidirectional{Bounce: 4}},
+	}
+	for _, test := range tests {
+		glass := &material.Uniform{
+			Color:        rgb.Energy{1, 1, 1},
+			Transmission: 0.5,
+			Interior:     &render.Medium{Absorb: rgb.Energy{absorb, absorb, absorb}},
+		} // with no specularity, light passes straight through the glass
+		ball := surface.UnitSphere(glass)
+		cam := camera.NewSLR().MoveTo(geom.Vec{0, 0, -3}).LookAt(geom.Vec{0, 0, 0})
+		cam.Lens = 0.5 // narrow enough that every pixel looks through the middle of the ball
+		scene := render.NewScene(cam, ball, sky{})
+		scene.Integrator = test.integrator
+		f := render.NewFrame(scene, w, h, 0, false, 1)
+		f.Limit(2000)
+		f.Start()
+		for f.Active() {
+			time.Sleep(time.Millisecond)
+		}
+		f.Stop()
+		s, _ := f.Sample()
+
+		total := 0.0
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				e, _ := s.At(x, y)
+				total += e.Mean()
+			}
+		}
+		got := total / (w * h) / sky{}.At(geom.Dir{}).Mean()
+		want := math.Exp(-absorb) // across the ball's diameter of 1
+		if math.Abs(got-want) > 0.03*want {
+			t.Errorf("%v: transmittance is %.3f, expected %.3f", test.name, got, want)
+		}
+	}
+}
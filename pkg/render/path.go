@@ -0,0 +1,253 @@
+package render
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// PathTracer is a unidirectional path tracer.
+// It follows paths from the camera for up to Bounce segments,
+// and when Direct is set it estimates direct lighting at each bounce
+// with multiple importance sampling of both the lights and the BSDF.
+// Paths keep track of the media they pass into through surfaces that transmit light,
+// and may scatter inside a medium before they reach the next surface, as if from a surface with the medium's phase function.
+// The camera is outside of every medium.
+type PathTracer struct {
+	Bounce int
+	Direct bool
+}
+
+// Li follows a path from the camera.
+// https://graphics.stanford.edu/courses/cs348b-03/papers/veach-chapter9.pdf
+// https://www.pbr-book.org/3ed-2018/Light_Transport_II_Volume_Rendering/Volumetric_Light_Transport
+func (p *PathTracer) Li(ray *geom.Ray, scene *Scene, rnd *rand.Rand) rgb.Energy {
+	energy := rgb.Black
+	signal := rgb.White
+	pdf := 0.0          // density of the BSDF sample that produced ray, or 0 if lights weren't sampled
+	var media []*Medium // that the path is inside, innermost last
+
+	for d := 0; d < p.Bounce; d++ {
+		obj, dist := scene.Surface.Intersect(ray, infinity)
+
+		medium := innermost(media)
+		if medium != nil {
+			max := dist
+			if obj == nil {
+				max = infinity
+			}
+			t, scattered, weight := medium.sample(max, rnd)
+			signal = signal.Times(weight)
+			if scattered {
+				pt := ray.Moved(t)
+				phase := medium.phase()
+				wo := ray.Dir.Inv() // in world space, which phase functions work in as well as any
+				wi, wiPDF, _ := phase.Sample(wo, rnd)
+				pdf = 0
+				if p.Direct {
+					energy = energy.Plus(scene.shadow(pt, ray.Time, wo, phase, geom.Identity(), medium, rnd).Times(signal))
+					pdf = wiPDF
+				}
+				if wiPDF <= 0 {
+					break
+				}
+				signal = signal.Times(phase.Eval(wi, wo).Scaled(1 / wiPDF)).RandomGain(rnd)
+				if signal.Zero() {
+					break
+				}
+				ray = geom.NewRayAt(pt, wi, ray.Time)
+				continue
+			}
+		}
+
+		if obj == nil {
+			env := scene.Env.At(ray.Dir).Times(signal)
+			energy = energy.Plus(env)
+			break
+		}
+		if l := obj.Light(); !l.Zero() {
+			weight := 1.0
+			if pdf > 0 {
+				weight = powerHeuristic(pdf, scene.lightPDF(obj, ray.Origin, ray.Dir, dist))
+			}
+			energy = energy.Plus(l.Times(signal).Scaled(weight))
+			break
+		}
+
+		pt := ray.Moved(dist)
+		normal, bsdf := obj.At(pt, ray.Dir, rnd)
+
+		if !ray.Dir.Enters(normal) && obj.Medium() == nil { // which has already absorbed light on the way
+			t := obj.Transmit()
+			// if t.Zero() {	// TODO: should this be removed again now that the triangle distance bug is fixed?
+			// 	ray = geom.NewRay(pt, ray.Dir)
+			// 	continue
+			// }
+			transmittance := beers(dist, t)
+			signal = signal.Times(transmittance)
+		}
+
+		toTan, fromTan := geom.Tangent(normal)
+		wo := toTan.MultDir(ray.Dir.Inv())
+		wi, wiPDF, shadow := bsdf.Sample(wo, rnd)
+
+		pdf = 0
+		if p.Direct && lit(bsdf, wo, shadow) {
+			energy = energy.Plus(scene.shadow(pt, ray.Time, wo, bsdf, toTan, medium, rnd).Times(signal))
+		}
+		if p.Direct && shadow {
+			pdf = wiPDF
+		}
+		if wiPDF <= 0 {
+			break
+		}
+
+		reflectance := bsdf.Eval(wi, wo).Scaled(1 / wiPDF).Limit(maxWeight)
+		bounce := fromTan.MultDir(wi)
+		if wi.Y*wo.Y < 0 {
+			media = cross(media, obj.Medium(), ray.Dir.Enters(normal))
+		}
+		signal = signal.Times(reflectance).RandomGain(rnd)
+
+		if signal.Zero() {
+			break
+		}
+
+		ray = geom.NewRayAt(pt, bounce, ray.Time)
+	}
+
+	return energy
+}
+
+// innermost returns the medium that a path inside media is in, or nil if it's in none.
+func innermost(media []*Medium) *Medium {
+	if len(media) == 0 {
+		return nil
+	}
+	return media[len(media)-1]
+}
+
+// cross returns the media that a path inside media is in after it passes into or out of a surface around medium m.
+func cross(media []*Medium, m *Medium, enters bool) []*Medium {
+	if m == nil {
+		return media
+	}
+	if enters {
+		return append(media[:len(media):len(media)], m)
+	}
+	for i := len(media) - 1; i >= 0; i-- {
+		if media[i] == m {
+			return append(media[:i:i], media[i+1:]...)
+		}
+	}
+	return media
+}
+
+// shadow estimates the light arriving directly at pt, at a given time, from a randomly chosen light,
+// weighted against the chance of the BSDF sampling the same direction.
+// Light is dimmed by the medium that pt is in, which must fill the space between pt and the light.
+// https://blog.yiningkarlli.com/2013/04/importance-sampled-direct-lighting.html
+func (s *Scene) shadow(pt geom.Vec, time float64, wo geom.Dir, bsdf BSDF, toTan *geom.Mtx, medium *Medium, rnd *rand.Rand) rgb.Energy {
+	l, prob := s.Lights.Choose(pt, rnd)
+	if l == nil || prob <= 0 {
+		return rgb.Black
+	}
+	dir, dist, pdf := sampleLight(l, pt, rnd)
+	pdf *= prob
+	if pdf <= 0 {
+		return rgb.Black
+	}
+
+	obj, d := s.Surface.Intersect(geom.NewRayAt(pt, dir, time), infinity)
+	if obj != l || d < dist*(1-lightBias) {
+		return rgb.Black
+	}
+
+	wi := toTan.MultDir(dir)
+	weight := powerHeuristic(pdf, bsdf.PDF(wi, wo))
+	return obj.Light().Times(bsdf.Eval(wi, wo)).Times(medium.transmittance(d)).Scaled(weight / pdf)
+}
+
+// sampleLight chooses a direction from pt towards a light, returning its density per steradian.
+// Emitters are sampled by area, so the sampled point is dist away and must be visible from pt;
+// other lights are sampled through their bounding spheres and return a dist of 0.
+func sampleLight(l Object, pt geom.Vec, rnd *rand.Rand) (dir geom.Dir, dist, pdf float64) {
+	e, ok := l.(Emitter)
+	if !ok {
+		dir, pdf = l.Bounds().SampleCone(pt, rnd)
+		return dir, 0, pdf
+	}
+	p, normal, pdf := e.SampleArea(rnd)
+	v := p.Minus(pt)
+	dist = v.Len()
+	dir, ok = v.Unit()
+	if !ok {
+		return dir, 0, 0
+	}
+	return dir, dist, solidAngle(pdf, dist, math.Abs(normal.Dot(dir)))
+}
+
+// lightPDF returns the density with which shadow chooses the direction dir from pt,
+// towards a light that is dist away along dir.
+func (s *Scene) lightPDF(obj Object, pt geom.Vec, dir geom.Dir, dist float64) float64 {
+	prob := s.Lights.Prob(obj, pt)
+	if prob <= 0 {
+		return 0
+	}
+	e, ok := obj.(Emitter)
+	if !ok {
+		return prob * obj.Bounds().ConePDF(pt, dir)
+	}
+	normal, pdf := e.AreaPDF(pt.Plus(dir.Scaled(dist)))
+	return prob * solidAngle(pdf, dist, math.Abs(normal.Dot(dir)))
+}
+
+// visible returns whether nothing lies between points a and b at a given time.
+func (s *Scene) visible(a, b geom.Vec, time float64) bool {
+	dir, dist, ok := direction(a, b)
+	if !ok {
+		return false
+	}
+	obj, _ := s.Surface.Intersect(geom.NewRayAt(a, dir, time), dist*(1-lightBias))
+	return obj == nil
+}
+
+// solidAngle converts a density per unit area into a density per steradian
+// for a point dist away whose surface is at cos to the line of sight.
+func solidAngle(pdf, dist, cos float64) float64 {
+	if cos <= 0 {
+		return 0
+	}
+	return pdf * dist * dist / cos
+}
+
+// powerHeuristic weights a sample taken with density a against another strategy with density b.
+func powerHeuristic(a, b float64) float64 {
+	a2, b2 := a*a, b*b
+	if a2+b2 == 0 {
+		return 0
+	}
+	return a2 / (a2 + b2)
+}
+
+// Beer's Law.
+// http://www.epolin.com/converting-absorbance-transmittance
+// https://en.wikipedia.org/wiki/Optical_depth
+func beers(dist float64, transmit rgb.Energy) rgb.Energy {
+	// Avoid edge cases
+	if dist == 0 || transmit.Zero() {
+		return rgb.White
+	}
+	// TODO: precompute this on materials, use absorption instead of transmission?
+	absorb := rgb.Energy{
+		X: 2 - math.Log10(transmit.X*100),
+		Y: 2 - math.Log10(transmit.Y*100),
+		Z: 2 - math.Log10(transmit.Z*100),
+	}
+	r := math.Exp(-absorb.X * dist)
+	g := math.Exp(-absorb.Y * dist)
+	b := math.Exp(-absorb.Z * dist)
+	return rgb.Energy{r, g, b}
+}
@@ -0,0 +1,87 @@
+package render_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hunterloftis/pbr2/pkg/bsdf"
+	"github.com/hunterloftis/pbr2/pkg/camera"
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// layered is a material with a Layered BSDF outside of its surface, and the boundary of its dielectric inside, like Uniform.
+type layered struct {
+	bsdf.Layered
+}
+
+func (l layered) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
+	if in.Dot(norm) > 0 {
+		return geom.Up, bsdf.Transmit{Specular: l.Specular, Roughness: l.Roughness, Multiplier: 1}
+	}
+	return geom.Up, l.Layered
+}
+
+func (l layered) Light() rgb.Energy {
+	return rgb.Black
+}
+
+func (l layered) Transmit() rgb.Energy {
+	return rgb.Black
+}
+
+func (l layered) Medium() *render.Medium {
+	return nil
+}
+
+// TestPathLayered checks that a floor whose BSDF mixes smooth transmission with lobes that can be lit by sampling lights
+// is as bright whether light is found by sampling lights or only by paths that hit them.
+func TestPathLayered(t *testing.T) {
+	const w, h = 16, 12
+	white := rgb.Energy{1, 1, 1}
+	tests := []struct {
+		name string
+		bsdf bsdf.Layered
+	}{
+		{"half glass", bsdf.Layered{Color: white, Specular: 0.04, Transmit: 0.5}},
+		{"clearcoat over glass", bsdf.Layered{Color: white, Specular: 0.04, Transmit: 1, Clearcoat: 1, ClearcoatRoughness: 0.4}},
+	}
+	for _, test := range tests {
+		sample := func(integrator render.Integrator, passes int) float64 {
+			floor := surface.UnitCube(layered{test.bsdf}).Shift(geom.Vec{0, -0.5, 0}).Scale(geom.Vec{4, 1, 4})
+			lamp := surface.UnitSphere(material.Light(75, 75, 75)).Shift(geom.Vec{-0.5, 1.2, 0.3}).Scale(geom.Vec{1.2, 1.2, 1.2})
+			cam := camera.NewSLR().MoveTo(geom.Vec{0, 1, -2.5}).LookAt(geom.Vec{0, 0, 0})
+			scene := render.NewScene(cam, surface.NewList(floor, lamp), dark{})
+			scene.Integrator = integrator
+			f := render.NewFrame(scene, w, h, 0, false, 3)
+			f.Limit(passes)
+			f.Start()
+			for f.Active() {
+				time.Sleep(time.Millisecond)
+			}
+			f.Stop()
+			s, _ := f.Sample()
+			total := 0.0
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					e, _ := s.At(x, y)
+					total += e.Mean()
+				}
+			}
+			return total
+		}
+		direct := sample(&render.PathTracer{Bounce: 6, Direct: true}, 400)
+		hit := sample(&render.PathTracer{Bounce: 6, Direct: false}, 2000)
+		if direct == 0 {
+			t.Fatalf("%v: the scene is black", test.name)
+		}
+		if diff := math.Abs(direct-hit) / direct; diff > 0.05 {
+			t.Errorf("%v: totals differ by %.1f%%: sampling lights %.1f, hitting them %.1f", test.name, diff*100, direct, hit)
+		}
+	}
+}
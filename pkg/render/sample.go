@@ -15,9 +15,13 @@ const (
 	green
 	blue
 	count
+	square
 	stride
 )
 
+// minSamples is the number of samples a pixel needs before its error can be estimated.
+const minSamples = 16
+
 // TODO: hide Width and Height (expose as Width()/Height() if necessary)
 type Sample struct {
 	Width  int
@@ -49,6 +53,43 @@ func (s *Sample) Add(x, y int, e rgb.Energy) {
 	s.data[i+green] += e.Y
 	s.data[i+blue] += e.Z
 	s.data[i+count]++
+	l := e.Mean()
+	s.data[i+square] += l * l
+}
+
+// Error estimates the relative standard error of the mean brightness at x, y.
+// Pixels without enough samples for an estimate have infinite error.
+// https://en.wikipedia.org/wiki/Standard_error
+func (s *Sample) Error(x, y int) float64 {
+	i := (y*s.Width + x) * stride
+	n := s.data[i+count]
+	if n < minSamples {
+		return math.Inf(1)
+	}
+	mean := (s.data[i+red] + s.data[i+green] + s.data[i+blue]) / (3 * n)
+	variance := (s.data[i+square]/n - mean*mean) * n / (n - 1)
+	if variance <= 0 {
+		return 0
+	}
+	if mean <= 0 {
+		return math.Inf(1)
+	}
+	return math.Sqrt(variance/n) / mean
+}
+
+// Pending returns a mask of the pixels whose Error is still above target.
+// The mask is indexed by y*Width + x.
+func (s *Sample) Pending(target float64) (mask []bool, remaining int) {
+	mask = make([]bool, s.Width*s.Height)
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			if s.Error(x, y) > target {
+				mask[y*s.Width+x] = true
+				remaining++
+			}
+		}
+	}
+	return mask, remaining
 }
 
 // http://www.dspguide.com/ch2/2.htm
@@ -65,14 +106,19 @@ func (s *Sample) Merge(other *Sample) {
 // (essentially a gaussian blur that ignores light < some threshold)
 func (s *Sample) Image() *image.RGBA {
 	im := image.NewRGBA(image.Rect(0, 0, int(s.Width), int(s.Height)))
+	s.draw(im, 0, 0)
+	return im
+}
+
+// draw renders the Sample into im with its top left corner at x0, y0.
+func (s *Sample) draw(im *image.RGBA, x0, y0 int) {
 	for y := 0; y < s.Height; y++ {
 		for x := 0; x < s.Width; x++ {
 			e, _ := s.At(x, y)
 			c := e.ToRGBA()
-			im.SetRGBA(x, y, c)
+			im.SetRGBA(x0+x, y0+y, c)
 		}
 	}
-	return im
 }
 
 func (s *Sample) Buffer() (*bytes.Buffer, error) {
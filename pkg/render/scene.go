@@ -1,9 +1,16 @@
 package render
 
+// Scene describes what to render.
+// Lights and Integrator are optional; when Lights is nil, lights are chosen in proportion to their power,
+// and when Integrator is nil, Frames render with a PathTracer.
 type Scene struct {
-	Camera  Camera
-	Env     Environment
-	Surface Surface
+	Camera     Camera
+	Env        Environment
+	Surface    Surface
+	Lights     LightSampler
+	Integrator Integrator
+
+	emitters *PowerLights // chooses the origins of light subpaths, independently of any point
 }
 
 func NewScene(c Camera, s Surface, e Environment) *Scene {
@@ -14,8 +21,22 @@ func NewScene(c Camera, s Surface, e Environment) *Scene {
 	}
 }
 
-func (s *Scene) Render(width, height, bounce int, direct bool) *Frame {
-	f := NewFrame(s, width, height, bounce, direct)
+func (s *Scene) Render(width, height, bounce int, direct bool, seed int64) *Frame {
+	f := NewFrame(s, width, height, bounce, direct, seed)
 	f.Start()
 	return f
 }
+
+// prepare returns a copy of the scene with its optional fields filled in.
+func (s *Scene) prepare(bounce int, direct bool) *Scene {
+	s2 := *s
+	lights := s.Surface.Lights()
+	if s2.Lights == nil {
+		s2.Lights = NewPowerLights(lights...)
+	}
+	if s2.Integrator == nil {
+		s2.Integrator = &PathTracer{Bounce: bounce, Direct: direct}
+	}
+	s2.emitters = NewPowerLights(Emitters(lights)...)
+	return &s2
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"image"
+	"sync"
+)
+
+// tileSize is the width and height in pixels of the units of work that tracers pull from a Frame.
+const tileSize = 32
+
+// tile is a rectangular region of a Frame that accumulates its own samples.
+// Passes over a tile are merged in the order they were issued,
+// so the accumulated sums don't depend on which tracer finished first.
+type tile struct {
+	index   int
+	x, y    int
+	mu      sync.Mutex
+	turn    *sync.Cond
+	data    *Sample
+	issued  int // passes handed out to tracers
+	merged  int // passes merged, in issue order
+	passes  int // passes merged since the last clear
+	limit   int
+	target  float64
+	pending []bool
+	done    bool
+}
+
+func newTiles(width, height int) []*tile {
+	tiles := make([]*tile, 0)
+	for y := 0; y < height; y += tileSize {
+		for x := 0; x < width; x += tileSize {
+			w, h := tileSize, tileSize
+			if x+w > width {
+				w = width - x
+			}
+			if y+h > height {
+				h = height - y
+			}
+			t := &tile{index: len(tiles), x: x, y: y, data: NewSample(w, h)}
+			t.turn = sync.NewCond(&t.mu)
+			tiles = append(tiles, t)
+		}
+	}
+	return tiles
+}
+
+// issue hands out the tile's next pass, or returns false if the tile has reached its pass limit.
+func (t *tile) issue() (pass int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.limit > 0 && t.passes+t.issued-t.merged >= t.limit {
+		return 0, false
+	}
+	pass = t.issued
+	t.issued++
+	return pass, true
+}
+
+// mask returns the pixels that still need samples in a pass (nil for every pixel)
+// and whether the whole tile has converged.
+// Adaptive tiles wait for earlier passes to be merged first, so the mask never depends on timing.
+// The returned slice is never modified after it is published.
+func (t *tile) mask(pass int) ([]bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.target > 0 && t.merged < pass {
+		t.turn.Wait()
+	}
+	return t.pending, t.done
+}
+
+// wait blocks until every pass issued before pass has been merged.
+func (t *tile) wait(pass int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.merged < pass {
+		t.turn.Wait()
+	}
+}
+
+// merge adds one pass of samples to the tile (s may be nil for a pass that sampled nothing).
+// It must only be called once wait has returned for the pass.
+// It returns true when this pass caused the tile to converge.
+func (t *tile) merge(s *Sample) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.turn.Broadcast()
+	if s != nil {
+		t.data.Merge(s)
+	}
+	t.merged++
+	t.passes++
+	if t.target <= 0 || t.done {
+		return false
+	}
+	remaining := 0
+	t.pending, remaining = t.data.Pending(t.target)
+	t.done = remaining == 0
+	return t.done
+}
+
+func (t *tile) adapt(target float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.target = target
+	t.pending = nil
+	t.done = false
+}
+
+func (t *tile) setLimit(passes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = passes
+}
+
+func (t *tile) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = NewSample(t.data.Width, t.data.Height)
+	t.passes = 0
+	t.pending = nil
+	t.done = false
+}
+
+// copyTo writes the tile's samples into s at the tile's position and returns its pass count.
+func (t *tile) copyTo(s *Sample) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for y := 0; y < t.data.Height; y++ {
+		src := t.data.data[y*t.data.Width*stride : (y+1)*t.data.Width*stride]
+		i := ((t.y+y)*s.Width + t.x) * stride
+		copy(s.data[i:i+len(src)], src)
+	}
+	return t.passes
+}
+
+// drawTo renders the tile into im at the tile's position and returns its pass count.
+func (t *tile) drawTo(im *image.RGBA) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data.draw(im, t.x, t.y)
+	return t.passes
+}
+
+func (t *tile) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.passes
+}
+
+// finished returns whether the tile has converged or completed its pass limit.
+func (t *tile) finished() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done || (t.limit > 0 && t.passes >= t.limit)
+}
@@ -3,7 +3,6 @@ package render
 import (
 	"math"
 	"math/rand"
-	"time"
 
 	"github.com/hunterloftis/pbr2/pkg/geom"
 	"github.com/hunterloftis/pbr2/pkg/rgb"
@@ -12,6 +11,7 @@ import (
 const (
 	maxWeight = 10
 	maxEnergy = 2000
+	lightBias = 1e-6 // relative distance by which a shadow ray may fall short of a sampled light point
 )
 
 var (
@@ -37,36 +37,54 @@ type Object interface {
 	Bounds() *geom.Bounds
 	Light() rgb.Energy    // TODO: rename to Emit()? Lumens()? <-- would need to actually be lumens in that case
 	Transmit() rgb.Energy // TODO: rename to Absorb() and precompute logarithms?
+	Medium() *Medium      // inside the object, or nil
 }
 
+// BSDF works in tangent space, where the surface normal is geom.Up.
+// Eval returns the reflectance towards wo of light arriving from wi, including the cosine term.
+// PDF returns the density (per steradian) with which Sample chooses wi.
+// Sample returns shadow = false for specular lobes that can't be lit by sampling lights directly.
 type BSDF interface {
 	Sample(wo geom.Dir, rnd *rand.Rand) (wi geom.Dir, pdf float64, shadow bool)
+	PDF(wi, wo geom.Dir) float64
 	Eval(wi, wo geom.Dir) rgb.Energy
 }
 
+// Mixed is a BSDF with both specular lobes and lobes that can be lit by sampling lights directly,
+// so whether one sample of it could be lit doesn't say whether the BSDF can be.
+// Lit returns whether any of its lobes that can be lit reflect light towards wo.
+type Mixed interface {
+	BSDF
+	Lit(wo geom.Dir) bool
+}
+
+// lit returns whether bsdf can be lit by sampling lights, given whether its sample towards wo could be.
+func lit(bsdf BSDF, wo geom.Dir, shadow bool) bool {
+	if m, ok := bsdf.(Mixed); ok {
+		return m.Lit(wo)
+	}
+	return shadow
+}
+
 type tracer struct {
+	frame  *Frame
 	scene  *Scene
-	out    chan *Sample
 	active toggle
 	rnd    *rand.Rand
-	local  *Sample
-	bounce int
-	direct bool
+	buffer []float64
 }
 
-func newTracer(s *Scene, o chan *Sample, w, h, bounce int, direct bool) *tracer {
+func newTracer(f *Frame) *tracer {
 	return &tracer{
-		scene:  s,
-		out:    o,
-		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
-		local:  NewSample(w, h),
-		bounce: bounce,
-		direct: direct,
+		frame: f,
+		scene: f.scene,
+		rnd:   rand.New(rand.NewSource(f.seed)),
 	}
 }
 
 func (t *tracer) start() {
 	if t.active.Set(true) {
+		t.frame.running.Add(1)
 		go t.process()
 	}
 }
@@ -76,125 +94,71 @@ func (t *tracer) stop() {
 }
 
 func (t *tracer) process() {
-	width := t.local.Width
-	height := t.local.Height
-	camera := t.scene.Camera
+	defer t.frame.running.Done()
 	for t.active.State() {
-		s := NewSample(width, height)
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				rx := float64(x) + t.rnd.Float64()
-				ry := float64(y) + t.rnd.Float64()
-				r := camera.Ray(rx, ry, float64(width), float64(height), t.rnd)
-				energy := t.trace(r, t.bounce).Limit(maxEnergy)
-				s.Add(x, y, energy)
-			}
+		version := t.frame.progress()
+		tl, pass, ok := t.frame.job()
+		if !ok {
+			// every pass has been issued; wait for other tracers to merge theirs, or for the limits to change
+			t.frame.finish()
+			t.frame.await(version)
+			continue
 		}
-		t.local.Merge(s)
-		t.out <- s
-	}
-}
-
-func (t *tracer) trace(ray *geom.Ray, depth int) rgb.Energy {
-	energy := rgb.Black
-	signal := rgb.White
-
-	for d := 0; d < depth; d++ {
-		obj, dist := t.scene.Surface.Intersect(ray, infinity)
-
-		if obj == nil {
-			env := t.scene.Env.At(ray.Dir).Times(signal)
-			energy = energy.Plus(env)
-			break
+		var s *Sample
+		t.rnd.Seed(jobSeed(t.frame.seed, tl.index, pass))
+		if pending, done := tl.mask(pass); !done {
+			s = t.pass(tl, pending)
 		}
-		if l := obj.Light(); !l.Zero() {
-			energy = energy.Plus(l.Times(signal))
-			break
+		tl.wait(pass)
+		if t.frame.merge(tl, s) || tl.finished() {
+			t.frame.finish()
 		}
+	}
+}
 
-		pt := ray.Moved(dist)
-		normal, bsdf := obj.At(pt, ray.Dir, t.rnd)
-
-		if !ray.Dir.Enters(normal) {
-			t := obj.Transmit()
-			// if t.Zero() {	// TODO: should this be removed again now that the triangle distance bug is fixed?
-			// 	ray = geom.NewRay(pt, ray.Dir)
-			// 	continue
-			// }
-			transmittance := beers(dist, t)
-			signal = signal.Times(transmittance)
-		}
-
-		toTan, fromTan := geom.Tangent(normal)
-		wo := toTan.MultDir(ray.Dir.Inv())
-		indirect := 1.0
-
-		wi, pdf, shadow := bsdf.Sample(wo, t.rnd)
-
-		if t.direct && shadow {
-			dir, light, coverage := t.shadow(pt, normal)
-			wiDirect := toTan.MultDir(dir)
-			if coverage > 0 {
-				reflectance := bsdf.Eval(wiDirect, wo).Scaled(coverage)
-				e := light.Times(reflectance).Times(signal)
-				energy = energy.Plus(e)
-				indirect -= coverage
+// pass takes one sample of each pending pixel in a tile.
+func (t *tracer) pass(tl *tile, pending []bool) *Sample {
+	width := float64(t.frame.width)
+	height := float64(t.frame.height)
+	camera := t.scene.Camera
+	s := t.scratch(tl.data.Width, tl.data.Height)
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			if pending != nil && !pending[y*s.Width+x] {
+				continue
 			}
+			rx := float64(tl.x+x) + t.rnd.Float64()
+			ry := float64(tl.y+y) + t.rnd.Float64()
+			r := camera.Ray(rx, ry, width, height, t.rnd)
+			energy := t.scene.Integrator.Li(r, t.scene, t.rnd)
+			s.Add(x, y, energy.Limit(maxEnergy))
 		}
-
-		weight := math.Min(maxWeight, indirect/pdf)
-		reflectance := bsdf.Eval(wi, wo).Scaled(weight)
-		bounce := fromTan.MultDir(wi)
-		signal = signal.Times(reflectance).RandomGain(t.rnd)
-
-		if signal.Zero() {
-			break
-		}
-
-		ray = geom.NewRay(pt, bounce)
 	}
-
-	return energy
+	return s
 }
 
-// https://blog.yiningkarlli.com/2013/04/importance-sampled-direct-lighting.html
-func (t *tracer) shadow(pt geom.Vec, normal geom.Dir) (wi geom.Dir, energy rgb.Energy, coverage float64) {
-	lights := t.scene.Surface.Lights()
-	if len(lights) < 1 {
-		return geom.Up, rgb.Black, 0
+// scratch returns an empty w x h Sample backed by the tracer's reusable buffer.
+func (t *tracer) scratch(w, h int) *Sample {
+	n := w * h * stride
+	if cap(t.buffer) < n {
+		t.buffer = make([]float64, n)
 	}
-	i := t.rnd.Intn(len(lights))
-	l := lights[i]
-
-	ray, coverage := l.Bounds().ShadowRay(pt, normal, t.rnd)
-	if coverage <= 0 {
-		return geom.Up, rgb.Black, 0
+	data := t.buffer[:n]
+	for i := range data {
+		data[i] = 0
 	}
-
-	obj, _ := t.scene.Surface.Intersect(ray, infinity)
-	if obj == nil {
-		return geom.Up, rgb.Black, 0
-	}
-
-	return ray.Dir, obj.Light(), coverage
+	return &Sample{Width: w, Height: h, data: data}
 }
 
-// Beer's Law.
-// http://www.epolin.com/converting-absorbance-transmittance
-// https://en.wikipedia.org/wiki/Optical_depth
-func beers(dist float64, transmit rgb.Energy) rgb.Energy {
-	// Avoid edge cases
-	if dist == 0 || transmit.Zero() {
-		return rgb.White
-	}
-	// TODO: precompute this on materials, use absorption instead of transmission?
-	absorb := rgb.Energy{
-		X: 2 - math.Log10(transmit.X*100),
-		Y: 2 - math.Log10(transmit.Y*100),
-		Z: 2 - math.Log10(transmit.Z*100),
+// jobSeed derives the random seed for one pass over one tile from a Frame's seed.
+// http://xoshiro.di.unimi.it/splitmix64.c
+func jobSeed(seed int64, tile, pass int) int64 {
+	x := uint64(seed)
+	for _, n := range [2]int{tile, pass} {
+		x += (uint64(n) + 1) * 0x9e3779b97f4a7c15
+		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+		x ^= x >> 31
 	}
-	r := math.Exp(-absorb.X * dist)
-	g := math.Exp(-absorb.Y * dist)
-	b := math.Exp(-absorb.Z * dist)
-	return rgb.Energy{r, g, b}
+	return int64(x)
 }
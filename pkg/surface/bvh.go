@@ -1,34 +1,320 @@
 package surface
 
 import (
+	"math"
+	"runtime"
+	"sync"
+
 	"github.com/hunterloftis/pbr2/pkg/geom"
 	"github.com/hunterloftis/pbr2/pkg/render"
 )
 
-// TODO: construct Bounding Volume Hierarchy
+const (
+	bvhBins     = 16      // candidate split planes per axis
+	bvhLeaf     = 4       // surfaces a leaf may hold before it's always split
+	bvhDepth    = 64      // deepest node, which bounds the traversal stack
+	bvhParallel = 1 << 14 // surfaces under a node before its children are built concurrently
+)
+
+// BVH is a bounding volume hierarchy.
+// Each surface is stored in exactly one leaf, and nodes are split where the surface area heuristic
+// predicts the cheapest traversal. The tree is flattened into an array in depth-first order,
+// so a node's first child directly follows it.
+// http://www.pbr-book.org/3ed-2018/Primitives_and_Intersection_Acceleration/Bounding_Volume_Hierarchies.html
+// Unbounded surfaces, like Planes, are kept outside of the hierarchy and tested separately.
 type BVH struct {
-	surfs  []render.Surface
-	lights []render.Object
-	bounds *geom.Bounds
+	surfs    []render.Surface
+	infinite []render.Surface
+	nodes    []bvhNode
+	lights   []render.Object
+	bounds   *geom.Bounds
+}
+
+type bvhNode struct {
+	box   box
+	index int32 // first surface of a leaf, or second child of an interior node
+	count int32 // surfaces in a leaf; zero for interior nodes
+	axis  int32 // axis along which an interior node was split
+}
+
+// box is an axis-aligned bounding box that is cheaper to grow than geom.Bounds.
+type box struct {
+	min, max [3]float64
+}
+
+func emptyBox() box {
+	inf := math.Inf(1)
+	return box{min: [3]float64{inf, inf, inf}, max: [3]float64{-inf, -inf, -inf}}
+}
+
+func (b *box) grow(b2 *box) {
+	for a := 0; a < 3; a++ {
+		if b2.min[a] < b.min[a] {
+			b.min[a] = b2.min[a]
+		}
+		if b2.max[a] > b.max[a] {
+			b.max[a] = b2.max[a]
+		}
+	}
+}
+
+func (b *box) add(pt [3]float64) {
+	b.grow(&box{min: pt, max: pt})
+}
+
+func (b *box) area() float64 {
+	x, y, z := b.max[0]-b.min[0], b.max[1]-b.min[1], b.max[2]-b.min[2]
+	if x < 0 || y < 0 || z < 0 {
+		return 0
+	}
+	return 2 * (x*y + y*z + z*x)
+}
+
+// check returns whether r enters the box before max.
+func (b *box) check(r *geom.Ray, max float64) bool {
+	tmin, tmax := bias, max
+	for a := 0; a < 3; a++ {
+		t0 := (b.min[a] - r.OrArray[a]) * r.InvArray[a]
+		t1 := (b.max[a] - r.OrArray[a]) * r.InvArray[a]
+		if r.InvArray[a] < 0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tmin {
+			tmin = t0
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+		if tmax < tmin {
+			return false
+		}
+	}
+	return true
+}
+
+// bvhItem is a surface (or a triangle of a Mesh) being sorted into a hierarchy.
+type bvhItem struct {
+	index int32 // of the surface in its original order
+	box   box
+}
+
+func (it *bvhItem) center(axis int) float64 {
+	return (it.box.min[axis] + it.box.max[axis]) * 0.5
+}
+
+// bvhBuild is a node of a hierarchy before it's flattened.
+type bvhBuild struct {
+	box          box
+	left, right  *bvhBuild
+	start, count int
+	axis         int
+	nodes        int // in this subtree, including itself
 }
 
 func NewBVH(ss ...render.Surface) *BVH {
 	b := BVH{
-		surfs:  ss,
 		bounds: BoundsAround(ss),
 	}
-	for _, s := range b.surfs {
+	for _, s := range ss {
 		b.lights = append(b.lights, s.Lights()...)
 	}
+	ss, b.infinite = partition(ss)
+	items := make([]bvhItem, len(ss))
+	for i, s := range ss {
+		bounds := s.Bounds()
+		items[i] = bvhItem{index: int32(i), box: box{min: bounds.MinArray, max: bounds.MaxArray}}
+	}
+	b.nodes = buildNodes(items)
+	b.surfs = make([]render.Surface, len(items))
+	for i := range items {
+		b.surfs[i] = ss[items[i].index]
+	}
 	return &b
 }
 
+// buildNodes builds a flattened hierarchy over items, reordering them so that each leaf
+// refers to a contiguous range of items.
+func buildNodes(items []bvhItem) []bvhNode {
+	if len(items) == 0 {
+		return nil
+	}
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	root := build(items, 0, 0, workers)
+	nodes := make([]bvhNode, 0, root.nodes)
+	return flatten(nodes, root)
+}
+
+// build sorts items into a subtree; start is the index of items[0] among all of the hierarchy's items.
+// Large subtrees build their left children in new goroutines while workers has room for them.
+func build(items []bvhItem, start, depth int, workers chan struct{}) *bvhBuild {
+	n := bvhBuild{box: emptyBox(), start: start, count: len(items), nodes: 1}
+	centers := emptyBox()
+	for i := range items {
+		n.box.grow(&items[i].box)
+		centers.add([3]float64{items[i].center(0), items[i].center(1), items[i].center(2)})
+	}
+	if len(items) <= 1 || depth >= bvhDepth {
+		return &n
+	}
+	mid, axis := split(items, &n.box, &centers)
+	if mid <= 0 {
+		return &n
+	}
+	n.axis = axis
+	next := depth + 1
+	if len(items) >= bvhParallel {
+		select {
+		case workers <- struct{}{}:
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				n.left = build(items[:mid], start, next, workers)
+				<-workers
+			}()
+			n.right = build(items[mid:], start+mid, next, workers)
+			wg.Wait()
+		default:
+		}
+	}
+	if n.left == nil {
+		n.left = build(items[:mid], start, next, workers)
+		n.right = build(items[mid:], start+mid, next, workers)
+	}
+	n.nodes += n.left.nodes + n.right.nodes
+	return &n
+}
+
+// split partitions items by the cheapest split plane according to the surface area heuristic,
+// binning items by their centers. It returns the number of items on the left of the plane,
+// or 0 if a leaf would be cheaper.
+// http://www.sci.utah.edu/~wald/Publications/2007/ParallelBVHBuild/fastbuild.pdf
+func split(items []bvhItem, bounds, centers *box) (mid, axis int) {
+	area := bounds.area()
+	best := math.Inf(1)
+	bestBin := -1
+	for a := 0; a < 3; a++ {
+		extent := centers.max[a] - centers.min[a]
+		if !(extent > 0) || math.IsInf(extent, 0) {
+			continue
+		}
+		var counts [bvhBins]int
+		var boxes [bvhBins]box
+		for i := range boxes {
+			boxes[i] = emptyBox()
+		}
+		for i := range items {
+			k := bin(items[i].center(a), centers.min[a], extent)
+			counts[k]++
+			boxes[k].grow(&items[i].box)
+		}
+		// costs[k] is the cost of splitting after bin k
+		var costs [bvhBins - 1]float64
+		right, count := emptyBox(), 0
+		for k := bvhBins - 1; k > 0; k-- {
+			right.grow(&boxes[k])
+			count += counts[k]
+			costs[k-1] = right.area() * float64(count)
+		}
+		left, count := emptyBox(), 0
+		for k := 0; k < bvhBins-1; k++ {
+			left.grow(&boxes[k])
+			count += counts[k]
+			if count == 0 || count == len(items) {
+				continue
+			}
+			if cost := costs[k] + left.area()*float64(count); cost < best {
+				best, bestBin, axis = cost, k, a
+			}
+		}
+	}
+	if bestBin < 0 {
+		if len(items) <= bvhLeaf {
+			return 0, 0
+		}
+		return len(items) / 2, 0 // every center is in the same place, so any split is as good as another
+	}
+	// traversing a node costs about as much as intersecting one surface
+	if area > 0 && 1+best/area >= float64(len(items)) && len(items) <= bvhLeaf {
+		return 0, 0
+	}
+	extent := centers.max[axis] - centers.min[axis]
+	for i := range items {
+		if bin(items[i].center(axis), centers.min[axis], extent) <= bestBin {
+			items[i], items[mid] = items[mid], items[i]
+			mid++
+		}
+	}
+	return mid, axis
+}
+
+func bin(center, min, extent float64) int {
+	k := int(bvhBins * (center - min) / extent)
+	if k >= bvhBins {
+		return bvhBins - 1
+	}
+	if k < 0 {
+		return 0
+	}
+	return k
+}
+
+func flatten(nodes []bvhNode, n *bvhBuild) []bvhNode {
+	i := len(nodes)
+	nodes = append(nodes, bvhNode{box: n.box})
+	if n.left == nil {
+		nodes[i].index, nodes[i].count = int32(n.start), int32(n.count)
+		return nodes
+	}
+	nodes = flatten(nodes, n.left)
+	nodes[i].index, nodes[i].axis = int32(len(nodes)), int32(n.axis)
+	return flatten(nodes, n.right)
+}
+
+// traverse calls leaf with the range of items in each leaf that r enters before max.
+// leaf returns the distance to the nearest hit so far, which culls any further nodes.
+// The nearer child of each node is visited first.
+func traverse(nodes []bvhNode, r *geom.Ray, max float64, leaf func(first, end int32) float64) {
+	if len(nodes) == 0 {
+		return
+	}
+	var stack [bvhDepth]int32
+	top := 0
+	i := int32(0)
+	for {
+		n := &nodes[i]
+		if n.box.check(r, max) {
+			if n.count == 0 {
+				if r.DirArray[n.axis] < 0 {
+					stack[top], i = i+1, n.index
+				} else {
+					stack[top], i = n.index, i+1
+				}
+				top++
+				continue
+			}
+			max = leaf(n.index, n.index+n.count)
+		}
+		if top == 0 {
+			return
+		}
+		top--
+		i = stack[top]
+	}
+}
+
 func (b *BVH) Intersect(r *geom.Ray, max float64) (obj render.Object, dist float64) {
 	dist = max
-	for _, s := range b.surfs {
-		if o, d := s.Intersect(r, dist); o != nil {
-			obj, dist = o, d
+	traverse(b.nodes, r, max, func(first, end int32) float64 {
+		for _, s := range b.surfs[first:end] {
+			if o, d := s.Intersect(r, dist); o != nil {
+				obj, dist = o, d
+			}
 		}
+		return dist
+	})
+	if o, d := intersectAll(b.infinite, r, dist); o != nil {
+		obj, dist = o, d
 	}
 	return obj, dist
 }
@@ -0,0 +1,98 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// mesh returns the triangles of a bumpy sphere with n bands of latitude and 2n of longitude.
+func mesh(n int) []render.Surface {
+	at := func(i, j int) geom.Vec {
+		theta, phi := math.Pi*float64(i)/float64(n), math.Pi*float64(j)/float64(n)
+		r := 1 + 0.1*math.Sin(5*theta)*math.Cos(7*phi)
+		return geom.Vec{math.Sin(theta) * math.Cos(phi), math.Cos(theta), math.Sin(theta) * math.Sin(phi)}.Scaled(r)
+	}
+	tris := make([]render.Surface, 0, 4*n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 2*n; j++ {
+			a, b, c, d := at(i, j), at(i+1, j), at(i+1, j+1), at(i, j+1)
+			tris = append(tris, surface.NewTriangle(a, b, c), surface.NewTriangle(a, c, d))
+		}
+	}
+	return tris
+}
+
+// rays returns rays from random points around the mesh towards random points inside it.
+func rays(count int) []*geom.Ray {
+	rnd := rand.New(rand.NewSource(1))
+	rs := make([]*geom.Ray, count)
+	for i := range rs {
+		from := geom.Vec(geom.RandDirection(rnd)).Scaled(3)
+		to := geom.Vec(geom.RandDirection(rnd)).Scaled(rnd.Float64())
+		dir, _ := to.Minus(from).Unit()
+		rs[i] = geom.NewRay(from, dir)
+	}
+	return rs
+}
+
+func TestBVH(t *testing.T) {
+	surfs := append(mesh(30),
+		surface.UnitSphere().Shift(geom.Vec{0, 0, 1.2}).Scale(geom.Vec{0.5, 0.5, 0.5}),
+		surface.UnitCube().Rotate(geom.Vec{0.3, 0.6, 0}),
+		surface.UnitCube().Shift(geom.Vec{0, -1.5, 0}).Scale(geom.Vec{100, 0.1, 100}),
+	)
+	list := surface.NewList(surfs...)
+	bvh := surface.NewBVH(surfs...)
+	for i, r := range rays(10000) {
+		for _, max := range []float64{math.Inf(1), 2.5} {
+			o1, d1 := list.Intersect(r, max)
+			o2, d2 := bvh.Intersect(r, max)
+			if o1 != o2 || (o1 != nil && math.Abs(d1-d2) > 1e-9) {
+				t.Fatalf("ray %v (max %v): list hit %p at %v, bvh hit %p at %v", i, max, o1, d1, o2, d2)
+			}
+		}
+	}
+}
+
+func TestBVHEmpty(t *testing.T) {
+	if o, _ := surface.NewBVH().Intersect(geom.NewRay(geom.Vec{}, geom.Up), math.Inf(1)); o != nil {
+		t.Errorf("empty BVH hit %v", o)
+	}
+}
+
+func benchmarkIntersect(b *testing.B, s render.Surface) {
+	rs := rays(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Intersect(rs[i%len(rs)], math.Inf(1))
+	}
+}
+
+func BenchmarkBVHIntersect(b *testing.B) {
+	benchmarkIntersect(b, surface.NewBVH(mesh(200)...))
+}
+
+func BenchmarkTreeIntersect(b *testing.B) {
+	benchmarkIntersect(b, surface.NewTree(mesh(200)...))
+}
+
+func BenchmarkBVHBuild(b *testing.B) {
+	tris := mesh(200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		surface.NewBVH(tris...)
+	}
+}
+
+func BenchmarkTreeBuild(b *testing.B) {
+	tris := mesh(200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		surface.NewTree(tris...)
+	}
+}
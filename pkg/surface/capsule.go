@@ -0,0 +1,92 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+)
+
+// Capsule describes a cylinder along the Y axis with hemispherical ends.
+// Untransformed, it's 1 unit tall and centered on the origin, with the given radius.
+// Texture coordinates wrap around it (u) from bottom to top (v).
+type Capsule struct {
+	shape
+	radius float64
+}
+
+// UnitCapsule returns a pointer to a new Capsule Surface of the given radius (up to 0.5),
+// with an optional material.
+func UnitCapsule(radius float64, m ...Material) *Capsule {
+	radius = math.Max(0, math.Min(0.5, radius))
+	return &Capsule{
+		shape:  newShape(geom.Vec{-radius, -0.5, -radius}, geom.Vec{radius, 0.5, radius}, m),
+		radius: radius,
+	}
+}
+
+func (c *Capsule) transform(m *geom.Mtx) *Capsule {
+	c.shape.transform(m)
+	return c
+}
+
+func (c *Capsule) Shift(v geom.Vec) *Capsule {
+	return c.transform(geom.Shift(v))
+}
+
+func (c *Capsule) Scale(v geom.Vec) *Capsule {
+	return c.transform(geom.Scale(v))
+}
+
+func (c *Capsule) Rotate(v geom.Vec) *Capsule {
+	return c.transform(geom.Rotate(v))
+}
+
+// Intersect tests the capsule's cylindrical side between the centers of its ends,
+// and each end's sphere beyond its center.
+func (c *Capsule) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	r := c.local(ray, max)
+	if r == nil {
+		return nil, 0
+	}
+	o, d := r.Origin, r.Dir
+	h, r2 := 0.5-c.radius, c.radius*c.radius
+	var ts [6]float64
+	hits := ts[:0]
+	for _, t := range quadratic(ts[:0], d.X*d.X+d.Z*d.Z, 2*(o.X*d.X+o.Z*d.Z), o.X*o.X+o.Z*o.Z-r2) {
+		if y := o.Y + d.Y*t; y >= -h && y <= h {
+			hits = append(hits, t)
+		}
+	}
+	for _, end := range []float64{-h, h} {
+		oc := geom.Vec{o.X, o.Y - end, o.Z}
+		b := oc.Dot(geom.Vec(d))
+		var roots [2]float64
+		for _, t := range quadratic(roots[:0], 1, 2*b, oc.Dot(oc)-r2) {
+			if y := o.Y + d.Y*t; (y-end)*end >= 0 {
+				hits = append(hits, t)
+			}
+		}
+	}
+	if dist, ok := c.nearest(r, hits, max); ok {
+		return c, dist
+	}
+	return nil, 0
+}
+
+func (c *Capsule) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	p := c.point(pt)
+	h := 0.5 - c.radius
+	axis := geom.Vec{0, math.Max(-h, math.Min(h, p.Y)), 0}
+	normal = c.normal(p.Minus(axis))
+	n2, bsdf := c.mat.At(angle(p.X, p.Z), p.Y+0.5, in, normal, rnd)
+	return c.shading(normal, geom.Vec{-p.Z, 0, p.X}, geom.Vec{0, 1, 0}, n2), bsdf
+}
+
+func (c *Capsule) Lights() []render.Object {
+	if !c.mat.Light().Zero() {
+		return []render.Object{c}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+)
+
+// Cone describes a capped cone along the Y axis.
+// Untransformed, its base is 1 unit across at y = -0.5 and its tip is at y = 0.5.
+// Texture coordinates wrap around its side (u) from bottom to top (v),
+// and are projected from below onto its base.
+type Cone struct {
+	shape
+}
+
+// UnitCone returns a pointer to a new Cone Surface with an optional material.
+func UnitCone(m ...Material) *Cone {
+	return &Cone{
+		shape: newShape(geom.Vec{-0.5, -0.5, -0.5}, geom.Vec{0.5, 0.5, 0.5}, m),
+	}
+}
+
+func (c *Cone) transform(m *geom.Mtx) *Cone {
+	c.shape.transform(m)
+	return c
+}
+
+func (c *Cone) Shift(v geom.Vec) *Cone {
+	return c.transform(geom.Shift(v))
+}
+
+func (c *Cone) Scale(v geom.Vec) *Cone {
+	return c.transform(geom.Scale(v))
+}
+
+func (c *Cone) Rotate(v geom.Vec) *Cone {
+	return c.transform(geom.Rotate(v))
+}
+
+// Intersect solves x² + z² = (k·h)² for the side, where h = 0.5 - y is the distance below the tip
+// and k = 0.5 is the ratio of radius to height.
+func (c *Cone) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	const k2 = 0.25
+	r := c.local(ray, max)
+	if r == nil {
+		return nil, 0
+	}
+	o, d := r.Origin, r.Dir
+	h := 0.5 - o.Y
+	a := d.X*d.X + d.Z*d.Z - k2*d.Y*d.Y
+	b := 2 * (o.X*d.X + o.Z*d.Z + k2*h*d.Y)
+	cc := o.X*o.X + o.Z*o.Z - k2*h*h
+	var ts [3]float64
+	hits := ts[:0]
+	for _, t := range quadratic(ts[:0], a, b, cc) {
+		if y := o.Y + d.Y*t; y >= -0.5 && y <= 0.5 {
+			hits = append(hits, t)
+		}
+	}
+	if d.Y != 0 {
+		t := (-0.5 - o.Y) / d.Y
+		if x, z := o.X+d.X*t, o.Z+d.Z*t; x*x+z*z <= 0.25 {
+			hits = append(hits, t)
+		}
+	}
+	if dist, ok := c.nearest(r, hits, max); ok {
+		return c, dist
+	}
+	return nil, 0
+}
+
+// At returns the normal and BSDF at a point on the cone's side or base, whichever pt is closer to.
+func (c *Cone) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	p := c.point(pt)
+	radial := math.Sqrt(p.X*p.X + p.Z*p.Z)
+	side := math.Abs(radial-0.5*(0.5-p.Y)) / math.Sqrt(1.25) // distance from the side
+	var u, v float64
+	var dpdu, dpdv geom.Vec
+	if math.Abs(p.Y+0.5) < side {
+		normal = c.normal(geom.Vec{0, -1, 0})
+		u, v = p.X+0.5, p.Z+0.5
+		dpdu, dpdv = geom.Vec{1, 0, 0}, geom.Vec{0, 0, 1}
+	} else {
+		normal = c.normal(geom.Vec{p.X, 0.5 * radial, p.Z}) // the side slopes 1 in 2
+		u, v = angle(p.X, p.Z), p.Y+0.5
+		dpdu, dpdv = geom.Vec{-p.Z, 0, p.X}, geom.Vec{0, 1, 0}
+	}
+	n2, bsdf := c.mat.At(u, v, in, normal, rnd)
+	return c.shading(normal, dpdu, dpdv, n2), bsdf
+}
+
+func (c *Cone) Lights() []render.Object {
+	if !c.mat.Light().Zero() {
+		return []render.Object{c}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// csgCrossings is the most times a ray is followed through one operand of a CSG.
+const csgCrossings = 64
+
+// csgMargin is how far outside the bounding sphere of an operand, relative to its radius, a ray is followed through it from.
+const csgMargin = 1e-3
+
+// csgGap is how far apart crossings must be, relative to the radius of the operand's bounding sphere,
+// so that rounding errors don't find the same crossing twice, or put one at a ray's origin in front of it.
+const csgGap = 1e-9
+
+type csgOp int
+
+const (
+	union csgOp = iota
+	intersection
+	difference
+)
+
+// CSG is a solid made by combining two closed surfaces with constructive solid geometry.
+// Each operand is followed along the ray from outside of it, so whether a ray starts inside it
+// comes from the number of times it crosses the operand's surface before the ray's origin,
+// and the CSG's surface is wherever the ray passes in or out of the combined solid.
+// Surfaces that a difference carves out of its first operand have their normals reversed,
+// and absorb light like the first operand, so refraction and absorption see a single solid.
+// https://en.wikipedia.org/wiki/Constructive_solid_geometry
+type CSG struct {
+	op     csgOp
+	a, b   render.Surface
+	bounds *geom.Bounds
+}
+
+// NewUnion returns the solid inside either a or b.
+func NewUnion(a, b render.Surface) *CSG {
+	return &CSG{op: union, a: a, b: b, bounds: geom.MergeBounds(a.Bounds(), b.Bounds())}
+}
+
+// NewIntersection returns the solid inside both a and b.
+func NewIntersection(a, b render.Surface) *CSG {
+	ab, bb := a.Bounds(), b.Bounds()
+	min, max := ab.Min.Max(bb.Min), ab.Max.Min(bb.Max)
+	return &CSG{op: intersection, a: a, b: b, bounds: geom.NewBounds(min, min.Max(max))}
+}
+
+// NewDifference returns the solid inside a but not b.
+func NewDifference(a, b render.Surface) *CSG {
+	return &CSG{op: difference, a: a, b: b, bounds: a.Bounds()}
+}
+
+func (c *CSG) inside(a, b bool) bool {
+	switch c.op {
+	case intersection:
+		return a && b
+	case difference:
+		return a && !b
+	}
+	return a || b
+}
+
+func (c *CSG) Intersect(r *geom.Ray, max float64) (obj render.Object, dist float64) {
+	if ok, near, _ := c.bounds.Check(r); !ok || near >= max {
+		return nil, 0
+	}
+	inA, as := crossings(c.a, r, max)
+	inB, bs := crossings(c.b, r, max)
+	in := c.inside(inA, inB)
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		var x crossing
+		if j >= len(bs) || (i < len(as) && as[i].dist <= bs[j].dist) {
+			x, inA = as[i], !inA
+			i++
+		} else {
+			x, inB = bs[j], !inB
+			j++
+			if c.op == difference && c.inside(inA, inB) != in && x.dist < max {
+				medium := x.obj
+				if i < len(as) {
+					medium = as[i].obj // where the ray leaves a
+				}
+				return &carved{obj: x.obj, medium: medium}, x.dist
+			}
+		}
+		if x.dist >= max {
+			break
+		}
+		if c.inside(inA, inB) != in {
+			return x.obj, x.dist
+		}
+	}
+	return nil, 0
+}
+
+// Lights returns nil; the parts of a light that a CSG would cut away can't be excluded
+// from light sampling, so CSG lights are only found by paths that hit them.
+func (c *CSG) Lights() []render.Object {
+	return nil
+}
+
+func (c *CSG) Bounds() *geom.Bounds {
+	return c.bounds
+}
+
+// crossing is a point where a ray passes through the surface of an operand.
+type crossing struct {
+	obj  render.Object
+	dist float64
+}
+
+// crossings returns whether r starts inside s, and every point along r where it passes through the surface of s,
+// nearest first, up to the first at or past max.
+// The ray is followed from just before where it enters the bounding sphere of s, where it's outside of s,
+// so it starts inside s if it crosses its surface an odd number of times before its origin.
+func crossings(s render.Surface, r *geom.Ray, max float64) (inside bool, cs []crossing) {
+	b := s.Bounds()
+	ok, _, far := b.Check(r)
+	if !ok {
+		return false, nil // and never will be
+	}
+	v := b.Center.Minus(r.Origin)
+	mid := v.Dot(geom.Vec(r.Dir)) // along the ray, to the point nearest the center
+	enter := mid - math.Sqrt(math.Max(0, b.Radius*b.Radius-(v.Dot(v)-mid*mid)))
+	margin, gap := b.Radius*csgMargin, b.Radius*csgGap
+	from := math.Min(0, enter) - margin
+	for n := 0; n < csgCrossings; n++ {
+		o, d := s.Intersect(geom.NewRayAt(r.Moved(from), r.Dir, r.Time), far-from+margin)
+		if o == nil {
+			break
+		}
+		t := from + d
+		from = t + gap
+		if t <= gap {
+			inside = !inside
+			continue
+		}
+		cs = append(cs, crossing{obj: o, dist: t})
+		if t >= max {
+			break
+		}
+	}
+	return inside, cs
+}
+
+// carved is part of the surface of a difference's second operand, seen from the other side.
+type carved struct {
+	obj    render.Object
+	medium render.Object // the part of the first operand that the ray leaves through
+}
+
+func (c *carved) At(pt geom.Vec, dir geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	n, bsdf := c.obj.At(pt, dir.Inv(), rnd)
+	return n.Inv(), bsdf
+}
+
+func (c *carved) Bounds() *geom.Bounds {
+	return c.obj.Bounds()
+}
+
+func (c *carved) Light() rgb.Energy {
+	return c.obj.Light()
+}
+
+func (c *carved) Transmit() rgb.Energy {
+	return c.medium.Transmit()
+}
+
+func (c *carved) Medium() *render.Medium {
+	return c.medium.Medium()
+}
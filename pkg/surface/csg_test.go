@@ -0,0 +1,92 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// TestCSG checks that rays stop where they pass in or out of each combined solid,
+// and that normals there point out of it, whether the solids are small, unit sized, or large.
+func TestCSG(t *testing.T) {
+	for _, scale := range []float64{1e-3, 1, 1e3} {
+		testCSG(t, scale)
+	}
+}
+
+func testCSG(t *testing.T, scale float64) {
+	// a unit cube at the origin and a sphere of radius 0.4 centered on one of its corners
+	inCube := func(p geom.Vec) bool {
+		return math.Abs(p.X) < 0.5 && math.Abs(p.Y) < 0.5 && math.Abs(p.Z) < 0.5
+	}
+	center := geom.Vec{0.5, 0.5, 0.5}
+	inSphere := func(p geom.Vec) bool { return p.Minus(center).Len() < 0.4 }
+	k := geom.Vec{scale, scale, scale}
+	cube := func() render.Surface { return surface.UnitCube(material.Plastic(1, 1, 1, 0.5)).Scale(k) }
+	sphere := func() render.Surface {
+		return surface.UnitSphere(material.Plastic(1, 1, 1, 0.5)).Scale(k).Shift(center).Scale(geom.Vec{0.8, 0.8, 0.8})
+	}
+	tests := []struct {
+		name   string
+		surf   render.Surface
+		inside func(p geom.Vec) bool
+	}{
+		{"union", surface.NewUnion(cube(), sphere()), func(p geom.Vec) bool { return inCube(p) || inSphere(p) }},
+		{"intersection", surface.NewIntersection(cube(), sphere()), func(p geom.Vec) bool { return inCube(p) && inSphere(p) }},
+		{"difference", surface.NewDifference(cube(), sphere()), func(p geom.Vec) bool { return inCube(p) && !inSphere(p) }},
+	}
+	eps := 1e-6 * scale
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		inside := func(p geom.Vec) bool { return test.inside(p.Scaled(1 / scale)) }
+		hits := 0
+		for i := 0; i < 2000; i++ {
+			// rays start both outside of and inside the solids
+			from := geom.Vec(geom.RandDirection(rnd)).Scaled(2 * rnd.Float64() * scale)
+			to := geom.Vec(geom.RandDirection(rnd)).Scaled(0.7 * rnd.Float64() * scale)
+			dir, _ := to.Minus(from).Unit()
+			r := geom.NewRay(from, dir)
+			was := inside(from)
+			// rays that pass through the surface carry on from it, as they do when they're refracted
+			for j := 0; j < 3; j++ {
+				obj, dist := test.surf.Intersect(r, math.Inf(1))
+				end := 4 * scale
+				if obj != nil {
+					end = dist
+				}
+				for d := 0.01 * scale; d < end-0.01*scale; d += 0.01 * scale {
+					if inside(r.Moved(d)) != was {
+						t.Fatalf("%v at scale %v: ray %v passes through the surface at %v before its hit at %v", test.name, scale, i, d, dist)
+					}
+				}
+				if obj == nil {
+					break
+				}
+				hits++
+				if o, _ := test.surf.Intersect(r, dist*0.999); o != nil {
+					t.Fatalf("%v at scale %v: ray %v hits before its hit at %v when limited to just before it", test.name, scale, i, dist)
+				}
+				if _, d := test.surf.Intersect(r, dist*1.001); d != dist {
+					t.Fatalf("%v at scale %v: ray %v hits at %v when limited to just after its hit at %v", test.name, scale, i, d, dist)
+				}
+				pt := r.Moved(dist)
+				if inside(r.Moved(dist+eps)) == was {
+					t.Fatalf("%v at scale %v: ray %v doesn't pass through the surface at %v", test.name, scale, i, dist)
+				}
+				n, _ := obj.At(pt, dir, rnd)
+				if inside(pt.Plus(n.Scaled(eps))) || !inside(pt.Minus(n.Scaled(eps))) {
+					t.Fatalf("%v at scale %v: ray %v: normal %v at %v doesn't point outwards", test.name, scale, i, n, pt)
+				}
+				r, was = geom.NewRay(pt, dir), !was
+			}
+		}
+		if hits < 100 {
+			t.Errorf("%v at scale %v: only %v rays hit", test.name, scale, hits)
+		}
+	}
+}
@@ -74,31 +74,99 @@ func (c *Cube) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bs
 	p1 := i.MultPoint(pt) // translate point into local space
 	abs := p1.Abs()
 	u, v := 0.0, 0.0
+	var dpdu, dpdv geom.Vec // directions in which u and v increase
 	switch {
 	case abs.X > abs.Y && abs.X > abs.Z:
 		normal = geom.Dir{math.Copysign(1, p1.X), 0, 0}
 		u = p1.Z + 0.5
 		v = p1.Y + 0.5
+		dpdu, dpdv = geom.Vec{0, 0, 1}, geom.Vec{0, 1, 0}
 	case abs.Y > abs.Z:
 		normal = geom.Dir{0, math.Copysign(1, p1.Y), 0}
 		u = p1.Z + 0.5
 		v = p1.X + 0.5
+		dpdu, dpdv = geom.Vec{0, 0, 1}, geom.Vec{1, 0, 0}
 	default:
 		normal = geom.Dir{0, 0, math.Copysign(1, p1.Z)}
 		u = p1.X + 0.5
 		v = p1.Y + 0.5
+		dpdu, dpdv = geom.Vec{1, 0, 0}, geom.Vec{0, 1, 0}
 	}
 	n := c.mtx.MultDir(normal)
 	n2, bsdf := c.mat.At(u, v, in, n, rnd)
-	_ = n2
-	normal = n // TODO: combine n and n2
-	return normal, bsdf
+	return shading(n, c.mtx.MultDist(dpdu), c.mtx.MultDist(dpdv), n2), bsdf
 }
 
 func (c *Cube) Bounds() *geom.Bounds {
 	return c.bounds
 }
 
+// Area returns the surface area of the transformed cube.
+func (c *Cube) Area() float64 {
+	return 2 * (c.faceArea(0) + c.faceArea(1) + c.faceArea(2))
+}
+
+// SampleArea returns a uniformly distributed point on the cube, its normal, and its density by area.
+// It first chooses a face in proportion to its transformed area.
+func (c *Cube) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	area := c.Area()
+	r := rnd.Float64() * area
+	axis := 0
+	for ; axis < 2; axis++ {
+		a := c.faceArea(axis) * 2
+		if r < a {
+			break
+		}
+		r -= a
+	}
+	p := [3]float64{rnd.Float64() - 0.5, rnd.Float64() - 0.5, rnd.Float64() - 0.5}
+	p[axis] = 0.5
+	if rnd.Float64() < 0.5 {
+		p[axis] = -0.5
+	}
+	pt = c.mtx.MultPoint(geom.ArrayToVec(p))
+	return pt, c.faceNormal(axis, p[axis]), 1 / area
+}
+
+// AreaPDF returns the normal at pt and the density with which SampleArea chooses it.
+func (c *Cube) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	p := c.mtx.Inverse().MultPoint(pt).Array()
+	axis := 0
+	for a := 1; a < 3; a++ {
+		if math.Abs(p[a]) > math.Abs(p[axis]) {
+			axis = a
+		}
+	}
+	return c.faceNormal(axis, p[axis]), 1 / c.Area()
+}
+
+// faceArea returns the transformed area of one of the two faces perpendicular to a local axis.
+func (c *Cube) faceArea(axis int) float64 {
+	u, v := c.faceEdges(axis)
+	return u.Cross(v).Len()
+}
+
+// faceNormal returns the geometric normal of the face perpendicular to a local axis, on the side of sign.
+func (c *Cube) faceNormal(axis int, sign float64) geom.Dir {
+	u, v := c.faceEdges(axis)
+	var out [3]float64
+	out[axis] = sign
+	n := u.Cross(v)
+	if n.Dot(c.mtx.MultDist(geom.ArrayToVec(out))) < 0 {
+		n = n.Scaled(-1)
+	}
+	normal, _ := n.Unit()
+	return normal
+}
+
+// faceEdges returns the transformed edges of a face perpendicular to a local axis.
+func (c *Cube) faceEdges(axis int) (u, v geom.Vec) {
+	var a, b [3]float64
+	a[(axis+1)%3] = 1
+	b[(axis+2)%3] = 1
+	return c.mtx.MultDist(geom.ArrayToVec(a)), c.mtx.MultDist(geom.ArrayToVec(b))
+}
+
 func (c *Cube) Lights() []render.Object {
 	if !c.mat.Light().Zero() {
 		return []render.Object{c}
@@ -114,6 +182,10 @@ func (c *Cube) Transmit() rgb.Energy {
 	return c.mat.Transmit()
 }
 
+func (c *Cube) Medium() *render.Medium {
+	return c.mat.Medium()
+}
+
 func (c *Cube) Shift(v geom.Vec) *Cube {
 	return c.transform(geom.Shift(v))
 }
@@ -144,5 +216,6 @@ func (c *Cube) transform(m *geom.Mtx) *Cube {
 		}
 	}
 	c.bounds = geom.NewBounds(min, max)
+	c.mtx.Prepare()
 	return c
 }
@@ -0,0 +1,103 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+)
+
+// Cylinder describes a cylinder along the Y axis.
+// Untransformed, it's 1 unit across and 1 unit tall, centered on the origin.
+// Texture coordinates wrap around its side (u) from bottom to top (v),
+// and are projected from above onto its caps.
+type Cylinder struct {
+	shape
+	capped bool
+}
+
+// UnitCylinder returns a pointer to a new capped Cylinder Surface with an optional material.
+func UnitCylinder(m ...Material) *Cylinder {
+	return &Cylinder{
+		shape:  newShape(geom.Vec{-0.5, -0.5, -0.5}, geom.Vec{0.5, 0.5, 0.5}, m),
+		capped: true,
+	}
+}
+
+// OpenCylinder returns a pointer to a new Cylinder Surface, without caps, with an optional material.
+func OpenCylinder(m ...Material) *Cylinder {
+	c := UnitCylinder(m...)
+	c.capped = false
+	return c
+}
+
+func (c *Cylinder) transform(m *geom.Mtx) *Cylinder {
+	c.shape.transform(m)
+	return c
+}
+
+func (c *Cylinder) Shift(v geom.Vec) *Cylinder {
+	return c.transform(geom.Shift(v))
+}
+
+func (c *Cylinder) Scale(v geom.Vec) *Cylinder {
+	return c.transform(geom.Scale(v))
+}
+
+func (c *Cylinder) Rotate(v geom.Vec) *Cylinder {
+	return c.transform(geom.Rotate(v))
+}
+
+func (c *Cylinder) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	r := c.local(ray, max)
+	if r == nil {
+		return nil, 0
+	}
+	o, d := r.Origin, r.Dir
+	var ts [4]float64
+	hits := ts[:0]
+	for _, t := range quadratic(ts[:0], d.X*d.X+d.Z*d.Z, 2*(o.X*d.X+o.Z*d.Z), o.X*o.X+o.Z*o.Z-0.25) {
+		if y := o.Y + d.Y*t; y >= -0.5 && y <= 0.5 {
+			hits = append(hits, t)
+		}
+	}
+	if c.capped && d.Y != 0 {
+		for _, y := range []float64{-0.5, 0.5} {
+			t := (y - o.Y) / d.Y
+			if x, z := o.X+d.X*t, o.Z+d.Z*t; x*x+z*z <= 0.25 {
+				hits = append(hits, t)
+			}
+		}
+	}
+	if dist, ok := c.nearest(r, hits, max); ok {
+		return c, dist
+	}
+	return nil, 0
+}
+
+// At returns the normal and BSDF at a point on the cylinder's side or caps, whichever pt is closer to.
+func (c *Cylinder) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	p := c.point(pt)
+	radial := math.Sqrt(p.X*p.X + p.Z*p.Z)
+	var u, v float64
+	var dpdu, dpdv geom.Vec
+	if c.capped && math.Abs(math.Abs(p.Y)-0.5) < math.Abs(radial-0.5) {
+		normal = c.normal(geom.Vec{0, math.Copysign(1, p.Y), 0})
+		u, v = p.X+0.5, p.Z+0.5
+		dpdu, dpdv = geom.Vec{1, 0, 0}, geom.Vec{0, 0, 1}
+	} else {
+		normal = c.normal(geom.Vec{p.X, 0, p.Z})
+		u, v = angle(p.X, p.Z), p.Y+0.5
+		dpdu, dpdv = geom.Vec{-p.Z, 0, p.X}, geom.Vec{0, 1, 0}
+	}
+	n2, bsdf := c.mat.At(u, v, in, normal, rnd)
+	return c.shading(normal, dpdu, dpdv, n2), bsdf
+}
+
+func (c *Cylinder) Lights() []render.Object {
+	if !c.mat.Light().Zero() {
+		return []render.Object{c}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Disk describes a flat circle (or, once scaled unevenly, an ellipse).
+// Untransformed, it has a diameter of 1 and is centered on the origin in the XZ plane, facing up (+Y).
+// Its texture coordinates are projected from its bounding square, from 0 to 1 along X and Z.
+type Disk struct {
+	flat
+	mat    Material
+	bounds *geom.Bounds
+}
+
+// UnitDisk returns a pointer to a new Disk Surface, 1 unit across, with an optional material.
+func UnitDisk(m ...Material) *Disk {
+	d := &Disk{
+		flat: flat{mtx: geom.Identity()},
+		mat:  &DefaultMaterial{},
+	}
+	if len(m) > 0 {
+		d.mat = m[0]
+	}
+	return d.transform(geom.Identity())
+}
+
+func (d *Disk) transform(m *geom.Mtx) *Disk {
+	d.flat.transform(m)
+	d.bounds = d.corners()
+	return d
+}
+
+func (d *Disk) Shift(v geom.Vec) *Disk {
+	return d.transform(geom.Shift(v))
+}
+
+func (d *Disk) Scale(v geom.Vec) *Disk {
+	return d.transform(geom.Scale(v))
+}
+
+func (d *Disk) Rotate(v geom.Vec) *Disk {
+	return d.transform(geom.Rotate(v))
+}
+
+func (d *Disk) Center() geom.Vec {
+	return d.mtx.MultPoint(geom.Vec{})
+}
+
+func (d *Disk) Bounds() *geom.Bounds {
+	return d.bounds
+}
+
+func (d *Disk) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	if ok, near, _ := d.bounds.Check(ray); !ok || near >= max {
+		return nil, 0
+	}
+	local, dist, ok := d.hit(ray, max)
+	if !ok || local.X*local.X+local.Z*local.Z > 0.25 {
+		return nil, 0
+	}
+	return d, dist
+}
+
+func (d *Disk) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	local := d.mtx.Inverse().MultPoint(pt)
+	n2, bsdf := d.mat.At(local.X+0.5, local.Z+0.5, in, d.normal, rnd)
+	return d.shading(n2), bsdf
+}
+
+// Area returns the surface area of the transformed disk.
+func (d *Disk) Area() float64 {
+	return math.Pi * 0.25 * d.stretch()
+}
+
+// SampleArea returns a uniformly distributed point on the disk, its normal, and its density by area.
+// http://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations.html#SamplingaUnitDisk
+func (d *Disk) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	r := 0.5 * math.Sqrt(rnd.Float64())
+	theta := 2 * math.Pi * rnd.Float64()
+	pt = d.mtx.MultPoint(geom.Vec{r * math.Cos(theta), 0, r * math.Sin(theta)})
+	return pt, d.normal, 1 / d.Area()
+}
+
+// AreaPDF returns the normal at pt and the density with which SampleArea chooses it.
+func (d *Disk) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	return d.normal, 1 / d.Area()
+}
+
+func (d *Disk) Lights() []render.Object {
+	if !d.mat.Light().Zero() {
+		return []render.Object{d}
+	}
+	return nil
+}
+
+func (d *Disk) Light() rgb.Energy {
+	return d.mat.Light()
+}
+
+func (d *Disk) Transmit() rgb.Energy {
+	return d.mat.Transmit()
+}
+
+func (d *Disk) Medium() *render.Medium {
+	return d.mat.Medium()
+}
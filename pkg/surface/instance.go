@@ -0,0 +1,194 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Instance places a shared Surface, such as a BVH of a mesh, into the scene with its own transform.
+// Many Instances can wrap the same Surface without copying it.
+// Rays are transformed into the Surface's local space to be intersected,
+// and the Objects they hit are wrapped so that their normals are transformed back out.
+type Instance struct {
+	surf    render.Surface
+	mtx     *geom.Mtx
+	normals *geom.Mtx // transforms local normals to world space
+	bounds  *geom.Bounds
+	lights  map[render.Object]render.Object // the Surface's lights, wrapped
+	list    []render.Object
+	wrapped []*instanced
+}
+
+// NewInstance returns an Instance of s with an optional transform.
+func NewInstance(s render.Surface, m ...*geom.Mtx) *Instance {
+	i := &Instance{
+		surf:   s,
+		mtx:    geom.Identity(),
+		lights: make(map[render.Object]render.Object),
+	}
+	for _, l := range s.Lights() {
+		inst := &instanced{obj: l}
+		i.lights[l] = inst
+		if _, ok := l.(render.Emitter); ok {
+			i.lights[l] = &instancedEmitter{inst}
+		}
+		i.list = append(i.list, i.lights[l])
+		i.wrapped = append(i.wrapped, inst)
+	}
+	if len(m) > 0 {
+		return i.transform(m[0])
+	}
+	return i.transform(geom.Identity())
+}
+
+func (i *Instance) transform(m *geom.Mtx) *Instance {
+	i.mtx = i.mtx.Mult(m)
+	i.normals = i.mtx.Inverse().Transpose()
+	i.bounds = i.transformBounds(i.surf.Bounds())
+	for _, l := range i.wrapped {
+		l.mtx, l.normals = i.mtx, i.normals
+		l.bounds = i.transformBounds(l.obj.Bounds())
+	}
+	return i
+}
+
+func (i *Instance) Shift(v geom.Vec) *Instance {
+	return i.transform(geom.Shift(v))
+}
+
+func (i *Instance) Scale(v geom.Vec) *Instance {
+	return i.transform(geom.Scale(v))
+}
+
+func (i *Instance) Rotate(v geom.Vec) *Instance {
+	return i.transform(geom.Rotate(v))
+}
+
+func (i *Instance) transformBounds(b *geom.Bounds) *geom.Bounds {
+	return transformBounds(i.mtx, b)
+}
+
+// transformBounds returns the world-space box around the corners of a local box.
+// Unbounded boxes stay unbounded.
+func transformBounds(mtx *geom.Mtx, b *geom.Bounds) *geom.Bounds {
+	if unbounded(b) {
+		return b
+	}
+	min := mtx.MultPoint(b.Min)
+	max := min
+	for _, x := range []float64{b.Min.X, b.Max.X} {
+		for _, y := range []float64{b.Min.Y, b.Max.Y} {
+			for _, z := range []float64{b.Min.Z, b.Max.Z} {
+				pt := mtx.MultPoint(geom.Vec{x, y, z})
+				min = min.Min(pt)
+				max = max.Max(pt)
+			}
+		}
+	}
+	return geom.NewBounds(min, max)
+}
+
+func (i *Instance) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	if ok, near, _ := i.bounds.Check(ray); !ok || near >= max {
+		return nil, 0
+	}
+	local, scale := localRay(i.mtx, ray)
+	o, d := i.surf.Intersect(local, max*scale)
+	if o == nil {
+		return nil, 0
+	}
+	if len(i.lights) > 0 {
+		if l, ok := i.lights[o]; ok {
+			return l, d / scale
+		}
+	}
+	return &instanced{mtx: i.mtx, normals: i.normals, obj: o}, d / scale
+}
+
+// localRay returns ray in the local space of mtx,
+// and the local distance along it per unit of world distance.
+func localRay(mtx *geom.Mtx, ray *geom.Ray) (*geom.Ray, float64) {
+	inv := mtx.Inverse()
+	dir := inv.MultDist(geom.Vec(ray.Dir))
+	unit, _ := dir.Unit()
+	return geom.NewRayAt(inv.MultPoint(ray.Origin), unit, ray.Time), dir.Len()
+}
+
+func (i *Instance) Lights() []render.Object {
+	return i.list
+}
+
+func (i *Instance) Bounds() *geom.Bounds {
+	return i.bounds
+}
+
+// instanced is an Object of a transformed Surface, such as an Instance's, seen through the transform.
+type instanced struct {
+	mtx     *geom.Mtx
+	normals *geom.Mtx // transforms local normals to world space
+	obj     render.Object
+	bounds  *geom.Bounds // cached for lights
+}
+
+func (o *instanced) At(pt geom.Vec, dir geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	inv := o.mtx.Inverse()
+	n, bsdf := o.obj.At(inv.MultPoint(pt), inv.MultDir(dir), rnd)
+	return o.normals.MultDir(n), bsdf
+}
+
+func (o *instanced) Bounds() *geom.Bounds {
+	if o.bounds != nil {
+		return o.bounds
+	}
+	return transformBounds(o.mtx, o.obj.Bounds())
+}
+
+func (o *instanced) Light() rgb.Energy {
+	return o.obj.Light()
+}
+
+func (o *instanced) Transmit() rgb.Energy {
+	return o.obj.Transmit()
+}
+
+func (o *instanced) Medium() *render.Medium {
+	return o.obj.Medium()
+}
+
+// volume returns the factor by which the transform scales volume.
+func (o *instanced) volume() float64 {
+	x := o.mtx.MultDist(geom.Vec{1, 0, 0})
+	y := o.mtx.MultDist(geom.Vec{0, 1, 0})
+	z := o.mtx.MultDist(geom.Vec{0, 0, 1})
+	return math.Abs(x.Dot(y.Cross(z)))
+}
+
+// stretch returns the factor by which the transform scales area on a local surface with normal n.
+// https://en.wikipedia.org/wiki/Normal_(geometry)#Transforming_normals
+func (o *instanced) stretch(n geom.Dir) float64 {
+	return o.volume() * o.normals.MultDist(geom.Vec(n)).Len()
+}
+
+// instancedEmitter is an instanced light that can be sampled by area.
+type instancedEmitter struct {
+	*instanced
+}
+
+// Area is exact for transforms that scale equally along each axis, and approximate otherwise.
+func (o *instancedEmitter) Area() float64 {
+	return o.obj.(render.Emitter).Area() * math.Pow(o.volume(), 2.0/3.0)
+}
+
+func (o *instancedEmitter) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	local, n, pdf := o.obj.(render.Emitter).SampleArea(rnd)
+	return o.mtx.MultPoint(local), o.normals.MultDir(n), pdf / o.stretch(n)
+}
+
+func (o *instancedEmitter) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	n, pdf := o.obj.(render.Emitter).AreaPDF(o.mtx.Inverse().MultPoint(pt))
+	return o.normals.MultDir(n), pdf / o.stretch(n)
+}
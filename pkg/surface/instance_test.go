@@ -0,0 +1,67 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+func TestInstance(t *testing.T) {
+	shift, rotate, scale := geom.Vec{0.2, -0.1, 0.3}, geom.Vec{0.4, 0.9, 0}, geom.Vec{0.8, 0.4, 0.6}
+	m := geom.Shift(shift).Mult(geom.Rotate(rotate)).Mult(geom.Scale(scale))
+	want := surface.UnitCube().Shift(shift).Rotate(rotate).Scale(scale)
+	got := surface.NewInstance(surface.NewBVH(surface.UnitCube()), m)
+	rnd := rand.New(rand.NewSource(1))
+	for i, r := range rays(2000) {
+		o1, d1 := want.Intersect(r, math.Inf(1))
+		o2, d2 := got.Intersect(r, math.Inf(1))
+		if (o1 == nil) != (o2 == nil) || math.Abs(d1-d2) > 1e-9 {
+			t.Fatalf("ray %v: cube hit %v at %v, instance hit %v at %v", i, o1, d1, o2, d2)
+		}
+		if o1 == nil {
+			continue
+		}
+		pt := r.Moved(d1)
+		n1, _ := o1.At(pt, r.Dir, rnd)
+		n2, _ := o2.At(pt, r.Dir, rnd)
+		if n2.Dot(n1) < 0.999999 && n2.Dot(n1) > -0.999999 {
+			t.Fatalf("ray %v: normal %v is not parallel to the cube's %v", i, n2, n1)
+		}
+	}
+}
+
+func TestInstanceLights(t *testing.T) {
+	lamp := surface.UnitSphere(material.Light(1, 1, 1))
+	inst := surface.NewInstance(surface.NewList(lamp, surface.UnitCube().Shift(geom.Vec{2, 0, 0}))).
+		Shift(geom.Vec{0, 3, 0}).Scale(geom.Vec{2, 2, 2})
+	lights := inst.Lights()
+	if len(lights) != 1 {
+		t.Fatalf("got %v lights, want 1", len(lights))
+	}
+	o, dist := inst.Intersect(geom.NewRay(geom.Vec{}, geom.Up), math.Inf(1))
+	if o != lights[0] || math.Abs(dist-2) > 1e-9 {
+		t.Errorf("hit %v at %v, want the light at 2", o, dist)
+	}
+	e, ok := lights[0].(render.Emitter)
+	if !ok {
+		t.Fatal("instanced sphere isn't an Emitter")
+	}
+	if a := e.Area(); math.Abs(a-4*math.Pi) > 1e-6 {
+		t.Errorf("area %v, want %v", a, 4*math.Pi)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		pt, _, pdf := e.SampleArea(rnd)
+		if d := pt.Minus(geom.Vec{0, 3, 0}).Len(); math.Abs(d-1) > 1e-9 {
+			t.Fatalf("sampled point %v is %v from the center, want 1", pt, d)
+		}
+		if _, pdf2 := e.AreaPDF(pt); math.Abs(pdf*4*math.Pi-1) > 1e-9 || math.Abs(pdf2-pdf) > 1e-12 {
+			t.Fatalf("pdf %v and %v, want %v", pdf, pdf2, 1/(4*math.Pi))
+		}
+	}
+}
@@ -9,17 +9,45 @@ import (
 	"github.com/hunterloftis/pbr2/pkg/rgb"
 )
 
+// Material describes a surface at texture coordinates u, v, where its normal is norm.
+// At returns the BSDF and the normal to shade with, in tangent space:
+// geom.Up leaves the surface normal as it is, and X and Z lean it towards increasing u and v.
 type Material interface {
 	At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF)
 	Light() rgb.Energy
 	Transmit() rgb.Energy
+	Medium() *render.Medium // inside closed surfaces, or nil
+}
+
+// shading returns the world-space normal that a Material's tangent-space normal, local, stands for
+// on a surface whose normal is n and whose texture coordinates increase along dpdu and dpdv.
+// The tangent is dpdu made perpendicular to n; dpdv only chooses which way the bitangent points.
+// https://learnopengl.com/Advanced-Lighting/Normal-Mapping
+func shading(n geom.Dir, dpdu, dpdv geom.Vec, local geom.Dir) geom.Dir {
+	if local == geom.Up || local == (geom.Dir{}) {
+		return n
+	}
+	t, ok := dpdu.Minus(n.Scaled(n.Dot(geom.Dir(dpdu)))).Unit()
+	if !ok {
+		_, fromTan := geom.Tangent(n) // texture coordinates don't change, so any tangent will do
+		t = fromTan.MultDir(geom.Dir{1, 0, 0})
+	}
+	b, _ := n.Cross(t)
+	if geom.Vec(b).Dot(dpdv) < 0 {
+		b = b.Inv()
+	}
+	shaded, ok := t.Scaled(local.X).Plus(n.Scaled(local.Y)).Plus(b.Scaled(local.Z)).Unit()
+	if !ok {
+		return n
+	}
+	return shaded
 }
 
 type DefaultMaterial struct {
 }
 
 func (d *DefaultMaterial) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
-	return norm, Lambert{}
+	return geom.Up, Lambert{}
 }
 
 func (d *DefaultMaterial) Light() rgb.Energy {
@@ -30,6 +58,10 @@ func (d *DefaultMaterial) Transmit() rgb.Energy {
 	return rgb.Black
 }
 
+func (d *DefaultMaterial) Medium() *render.Medium {
+	return nil
+}
+
 type Lambert struct {
 }
 
@@ -39,11 +71,11 @@ func (l Lambert) Sample(wo geom.Dir, rnd *rand.Rand) (geom.Dir, float64, bool) {
 }
 
 func (l Lambert) PDF(wi, wo geom.Dir) float64 {
-	return wi.Dot(geom.Up) * math.Pi
+	return math.Max(0, wi.Dot(geom.Up)) / math.Pi
 }
 
 func (l Lambert) Eval(wi, wo geom.Dir) rgb.Energy {
-	return rgb.White
+	return rgb.White.Scaled(math.Max(0, wi.Dot(geom.Up)) / math.Pi)
 }
 
 func (l Lambert) Emit() rgb.Energy {
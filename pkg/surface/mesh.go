@@ -0,0 +1,402 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Vertex is a corner shared by the faces of a Mesh.
+// A zero Normal means the vertex has none, so the faces that use it are flat.
+type Vertex struct {
+	Point   geom.Vec
+	Normal  geom.Dir
+	Texture geom.Vec
+}
+
+// Face is a triangle of a Mesh.
+type Face struct {
+	Verts [3]int // indices of its vertices
+	Mat   int    // index of its material
+}
+
+// Mesh is a triangle mesh that stores its vertices once, in shared arrays,
+// and its triangles as indices into them, with its own bounding volume hierarchy.
+// The Objects it returns are small values that refer back to the Mesh,
+// so it doesn't need a Go object per triangle.
+type Mesh struct {
+	verts  vertices
+	faces  []face
+	mats   []Material
+	nodes  []bvhNode
+	lights []render.Object
+	bounds *geom.Bounds
+}
+
+type face struct {
+	verts [3]int32
+	mat   int32
+}
+
+// vertices stores the attributes of a Mesh's vertices at some precision.
+type vertices interface {
+	point(i int32) geom.Vec
+	normal(i int32) geom.Dir // zero if the vertex has no normal
+	texture(i int32) geom.Vec
+	tangent(i int32) geom.Vec // zero if the vertex has no texture coordinates
+}
+
+// NewMesh returns a Mesh of faces made from verts, each with one of mats.
+// Compact meshes store vertices as float32s, which halves their size.
+// Normals and texture coordinates are only stored if some vertex has them,
+// and tangents, for normal mapping, are found from the texture coordinates.
+func NewMesh(verts []Vertex, faces []Face, mats []Material, compact bool) *Mesh {
+	m := Mesh{
+		faces: make([]face, len(faces)),
+		mats:  mats,
+	}
+	tangents := vertexTangents(verts, faces)
+	if compact {
+		m.verts = newVertices32(verts, tangents)
+	} else {
+		m.verts = newVertices64(verts, tangents)
+	}
+	items := make([]bvhItem, len(faces))
+	min, max := geom.Vec{}, geom.Vec{}
+	for i, f := range faces {
+		m.faces[i] = face{verts: [3]int32{int32(f.Verts[0]), int32(f.Verts[1]), int32(f.Verts[2])}, mat: int32(f.Mat)}
+		b := emptyBox()
+		for _, v := range m.faces[i].verts {
+			b.add(m.verts.point(v).Array())
+		}
+		items[i] = bvhItem{index: int32(i), box: b}
+		lo, hi := geom.ArrayToVec(b.min), geom.ArrayToVec(b.max)
+		if i == 0 {
+			min, max = lo, hi
+		}
+		min, max = min.Min(lo), max.Max(hi)
+	}
+	m.bounds = geom.NewBounds(min, max)
+	m.nodes = buildNodes(items)
+	sorted := make([]face, len(items))
+	for i := range items {
+		sorted[i] = m.faces[items[i].index]
+	}
+	m.faces = sorted
+	for i, f := range m.faces {
+		if !m.mats[f.mat].Light().Zero() {
+			m.lights = append(m.lights, meshFace{mesh: &m, i: int32(i)})
+		}
+	}
+	return &m
+}
+
+// vertexTangents returns the direction in which u increases at each vertex,
+// averaged over the faces around it and weighted by their areas,
+// or nil if no vertex has texture coordinates.
+func vertexTangents(verts []Vertex, faces []Face) []geom.Vec {
+	textured := false
+	for _, v := range verts {
+		textured = textured || v.Texture != (geom.Vec{})
+	}
+	if !textured {
+		return nil
+	}
+	ts := make([]geom.Vec, len(verts))
+	for _, f := range faces {
+		a, b, c := verts[f.Verts[0]], verts[f.Verts[1]], verts[f.Verts[2]]
+		edge1, edge2 := b.Point.Minus(a.Point), c.Point.Minus(a.Point)
+		dpdu, _ := derivatives(edge1, edge2, b.Texture.Minus(a.Texture), c.Texture.Minus(a.Texture))
+		t, ok := dpdu.Unit()
+		if !ok {
+			continue
+		}
+		area := edge1.Cross(edge2).Len() * 0.5
+		for _, i := range f.Verts {
+			ts[i] = ts[i].Plus(t.Scaled(area))
+		}
+	}
+	return ts
+}
+
+// Len returns the number of triangles in the mesh.
+func (m *Mesh) Len() int {
+	return len(m.faces)
+}
+
+func (m *Mesh) Intersect(r *geom.Ray, max float64) (obj render.Object, dist float64) {
+	dist = max
+	hit := int32(-1)
+	traverse(m.nodes, r, max, func(first, end int32) float64 {
+		for i := first; i < end; i++ {
+			if d, ok := m.intersect(i, r, dist); ok {
+				hit, dist = i, d
+			}
+		}
+		return dist
+	})
+	if hit < 0 {
+		return nil, dist
+	}
+	return meshFace{mesh: m, i: hit}, dist
+}
+
+// intersect tests whether r hits face i before max.
+// https://en.wikipedia.org/wiki/M%C3%B6ller%E2%80%93Trumbore_intersection_algorithm
+func (m *Mesh) intersect(i int32, ray *geom.Ray, max float64) (float64, bool) {
+	p0, edge1, edge2 := m.edges(i)
+	h := geom.Vec(ray.Dir).Cross(edge2)
+	a := edge1.Dot(h)
+	if a > -bias && a < bias {
+		return 0, false
+	}
+	f := 1 / a
+	s := ray.Origin.Minus(p0)
+	u := f * s.Dot(h)
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := s.Cross(edge1)
+	v := f * geom.Vec(ray.Dir).Dot(q)
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+	dist := f * edge2.Dot(q)
+	if dist <= bias || dist >= max {
+		return 0, false
+	}
+	return dist, true
+}
+
+// edges returns the first point of face i and the edges from it to the other two.
+func (m *Mesh) edges(i int32) (p0, edge1, edge2 geom.Vec) {
+	v := m.faces[i].verts
+	p0 = m.verts.point(v[0])
+	return p0, m.verts.point(v[1]).Minus(p0), m.verts.point(v[2]).Minus(p0)
+}
+
+func (m *Mesh) Lights() []render.Object {
+	return m.lights
+}
+
+func (m *Mesh) Bounds() *geom.Bounds {
+	return m.bounds
+}
+
+// meshFace is a triangle of a Mesh.
+// Values that refer to the same triangle are equal, so they can be compared and used as map keys.
+type meshFace struct {
+	mesh *Mesh
+	i    int32
+}
+
+// At returns the interpolated normal, and the material, at a point on the triangle.
+func (f meshFace) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
+	m := f.mesh
+	v := m.faces[f.i].verts
+	p0, edge1, edge2 := m.edges(f.i)
+	b1, b2 := bary(pt.Minus(p0), edge1, edge2)
+	weights := [3]float64{1 - b1 - b2, b1, b2}
+	var n, tex geom.Vec
+	smooth := true
+	for k := 0; k < 3; k++ {
+		vn := m.verts.normal(v[k])
+		smooth = smooth && vn != geom.Dir{}
+		n = n.Plus(vn.Scaled(weights[k]))
+		tex = tex.Plus(m.verts.texture(v[k]).Scaled(weights[k]))
+	}
+	normal, ok := n.Unit()
+	if !smooth || !ok {
+		normal, _ = edge1.Cross(edge2).Unit()
+	}
+	n2, bsdf := m.mats[m.faces[f.i].mat].At(tex.X, tex.Y, in, normal, rnd)
+	if n2 == geom.Up {
+		return normal, bsdf
+	}
+	t0 := m.verts.texture(v[0])
+	dpdu, dpdv := derivatives(edge1, edge2, m.verts.texture(v[1]).Minus(t0), m.verts.texture(v[2]).Minus(t0))
+	if smooth {
+		var t geom.Vec
+		for k := 0; k < 3; k++ {
+			t = t.Plus(m.verts.tangent(v[k]).Scaled(weights[k]))
+		}
+		if t != (geom.Vec{}) {
+			dpdu = t
+		}
+	}
+	return shading(normal, dpdu, dpdv, n2), bsdf
+}
+
+// bary returns the barycentric coordinates of p, relative to the first point of a triangle,
+// along each of its edges.
+// https://codeplea.com/triangular-interpolation
+func bary(p, edge1, edge2 geom.Vec) (b1, b2 float64) {
+	d00 := edge1.Dot(edge1)
+	d01 := edge1.Dot(edge2)
+	d11 := edge2.Dot(edge2)
+	d20 := p.Dot(edge1)
+	d21 := p.Dot(edge2)
+	d := d00*d11 - d01*d01
+	return (d11*d20 - d01*d21) / d, (d00*d21 - d01*d20) / d
+}
+
+func (f meshFace) Bounds() *geom.Bounds {
+	b := emptyBox()
+	for _, v := range f.mesh.faces[f.i].verts {
+		b.add(f.mesh.verts.point(v).Array())
+	}
+	return geom.NewBounds(geom.ArrayToVec(b.min), geom.ArrayToVec(b.max))
+}
+
+func (f meshFace) Light() rgb.Energy {
+	return f.mesh.mats[f.mesh.faces[f.i].mat].Light()
+}
+
+func (f meshFace) Transmit() rgb.Energy {
+	return f.mesh.mats[f.mesh.faces[f.i].mat].Transmit()
+}
+
+func (f meshFace) Medium() *render.Medium {
+	return f.mesh.mats[f.mesh.faces[f.i].mat].Medium()
+}
+
+// Area returns the surface area of the triangle.
+func (f meshFace) Area() float64 {
+	_, edge1, edge2 := f.mesh.edges(f.i)
+	return edge1.Cross(edge2).Len() * 0.5
+}
+
+// SampleArea returns a uniformly distributed point on the triangle, its geometric normal, and its density by area.
+func (f meshFace) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	p0, edge1, edge2 := f.mesh.edges(f.i)
+	su := math.Sqrt(rnd.Float64())
+	u, v := 1-su, rnd.Float64()*su
+	pt = p0.Plus(edge1.Scaled(u)).Plus(edge2.Scaled(v))
+	normal, pdf = f.AreaPDF(pt)
+	return pt, normal, pdf
+}
+
+// AreaPDF returns the geometric normal at pt and the density with which SampleArea chooses it.
+func (f meshFace) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	_, edge1, edge2 := f.mesh.edges(f.i)
+	n := edge1.Cross(edge2)
+	normal, _ = n.Unit()
+	return normal, 2 / n.Len()
+}
+
+type vertices64 struct {
+	points   []geom.Vec
+	normals  []geom.Dir
+	textures [][2]float64
+	tangents []geom.Vec
+}
+
+func newVertices64(verts []Vertex, tangents []geom.Vec) *vertices64 {
+	vv := vertices64{points: make([]geom.Vec, len(verts)), tangents: tangents}
+	for i, v := range verts {
+		vv.points[i] = v.Point
+		if v.Normal != (geom.Dir{}) {
+			if vv.normals == nil {
+				vv.normals = make([]geom.Dir, len(verts))
+			}
+			vv.normals[i] = v.Normal
+		}
+		if v.Texture != (geom.Vec{}) {
+			if vv.textures == nil {
+				vv.textures = make([][2]float64, len(verts))
+			}
+			vv.textures[i] = [2]float64{v.Texture.X, v.Texture.Y}
+		}
+	}
+	return &vv
+}
+
+func (vv *vertices64) point(i int32) geom.Vec {
+	return vv.points[i]
+}
+
+func (vv *vertices64) normal(i int32) geom.Dir {
+	if vv.normals == nil {
+		return geom.Dir{}
+	}
+	return vv.normals[i]
+}
+
+func (vv *vertices64) texture(i int32) geom.Vec {
+	if vv.textures == nil {
+		return geom.Vec{}
+	}
+	return geom.Vec{vv.textures[i][0], vv.textures[i][1], 0}
+}
+
+func (vv *vertices64) tangent(i int32) geom.Vec {
+	if vv.tangents == nil {
+		return geom.Vec{}
+	}
+	return vv.tangents[i]
+}
+
+type vertices32 struct {
+	points   [][3]float32
+	normals  [][3]float32
+	textures [][2]float32
+	tangents [][3]float32
+}
+
+func newVertices32(verts []Vertex, tangents []geom.Vec) *vertices32 {
+	vv := vertices32{points: make([][3]float32, len(verts))}
+	if tangents != nil {
+		vv.tangents = make([][3]float32, len(tangents))
+		for i, t := range tangents {
+			vv.tangents[i] = [3]float32{float32(t.X), float32(t.Y), float32(t.Z)}
+		}
+	}
+	for i, v := range verts {
+		vv.points[i] = [3]float32{float32(v.Point.X), float32(v.Point.Y), float32(v.Point.Z)}
+		if v.Normal != (geom.Dir{}) {
+			if vv.normals == nil {
+				vv.normals = make([][3]float32, len(verts))
+			}
+			vv.normals[i] = [3]float32{float32(v.Normal.X), float32(v.Normal.Y), float32(v.Normal.Z)}
+		}
+		if v.Texture != (geom.Vec{}) {
+			if vv.textures == nil {
+				vv.textures = make([][2]float32, len(verts))
+			}
+			vv.textures[i] = [2]float32{float32(v.Texture.X), float32(v.Texture.Y)}
+		}
+	}
+	return &vv
+}
+
+func (vv *vertices32) point(i int32) geom.Vec {
+	p := vv.points[i]
+	return geom.Vec{float64(p[0]), float64(p[1]), float64(p[2])}
+}
+
+func (vv *vertices32) normal(i int32) geom.Dir {
+	if vv.normals == nil {
+		return geom.Dir{}
+	}
+	n := vv.normals[i]
+	return geom.Dir{float64(n[0]), float64(n[1]), float64(n[2])}
+}
+
+func (vv *vertices32) texture(i int32) geom.Vec {
+	if vv.textures == nil {
+		return geom.Vec{}
+	}
+	t := vv.textures[i]
+	return geom.Vec{float64(t[0]), float64(t[1]), 0}
+}
+
+func (vv *vertices32) tangent(i int32) geom.Vec {
+	if vv.tangents == nil {
+		return geom.Vec{}
+	}
+	t := vv.tangents[i]
+	return geom.Vec{float64(t[0]), float64(t[1]), float64(t[2])}
+}
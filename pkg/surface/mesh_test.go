@@ -0,0 +1,78 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/material"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+func TestMesh(t *testing.T) {
+	tris := mesh(20)
+	verts := make([]surface.Vertex, 0, 3*len(tris))
+	faces := make([]surface.Face, len(tris))
+	for i, s := range tris {
+		tri := s.(*surface.Triangle)
+		for k := 0; k < 3; k++ {
+			faces[i].Verts[k] = len(verts)
+			verts = append(verts, surface.Vertex{Point: tri.Points[k]})
+		}
+	}
+	list := surface.NewList(tris...)
+	mats := []surface.Material{&surface.DefaultMaterial{}}
+	rnd := rand.New(rand.NewSource(1))
+	for _, compact := range []bool{false, true} {
+		m := surface.NewMesh(verts, faces, mats, compact)
+		tolerance := 1e-9
+		if compact {
+			tolerance = 1e-5
+		}
+		misses := 0
+		for i, r := range rays(5000) {
+			o1, d1 := list.Intersect(r, math.Inf(1))
+			o2, d2 := m.Intersect(r, math.Inf(1))
+			if (o1 == nil) != (o2 == nil) {
+				misses++ // rays can slip between single precision triangles along their edges
+				continue
+			}
+			if o1 == nil {
+				continue
+			}
+			if math.Abs(d1-d2) > tolerance {
+				t.Fatalf("ray %v (compact %v): triangle hit at %v, mesh hit at %v", i, compact, d1, d2)
+			}
+			n1, _ := o1.At(r.Moved(d1), r.Dir, rnd)
+			n2, _ := o2.At(r.Moved(d2), r.Dir, rnd)
+			if n1.Dot(n2) < 1-tolerance {
+				t.Fatalf("ray %v (compact %v): triangle normal %v, mesh normal %v", i, compact, n1, n2)
+			}
+		}
+		if misses > 5 {
+			t.Errorf("compact %v: %v rays hit only one of the mesh and the triangles", compact, misses)
+		}
+	}
+}
+
+func TestMeshLights(t *testing.T) {
+	verts := []surface.Vertex{
+		{Point: geom.Vec{0, 0, 0}}, {Point: geom.Vec{1, 0, 0}}, {Point: geom.Vec{0, 1, 0}}, {Point: geom.Vec{1, 1, 0}},
+	}
+	faces := []surface.Face{{Verts: [3]int{0, 1, 2}, Mat: 0}, {Verts: [3]int{1, 3, 2}, Mat: 1}}
+	mats := []surface.Material{&surface.DefaultMaterial{}, material.Light(1, 1, 1)}
+	m := surface.NewMesh(verts, faces, mats, false)
+	lights := m.Lights()
+	if len(lights) != 1 {
+		t.Fatalf("got %v lights, want 1", len(lights))
+	}
+	o, _ := m.Intersect(geom.NewRay(geom.Vec{0.8, 0.8, -1}, geom.Dir{0, 0, 1}), math.Inf(1))
+	if o != lights[0] {
+		t.Errorf("hit %v, want the light %v", o, lights[0])
+	}
+	if a := lights[0].(render.Emitter).Area(); a != 0.5 {
+		t.Errorf("light area %v, want 0.5", a)
+	}
+}
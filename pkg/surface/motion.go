@@ -0,0 +1,80 @@
+package surface
+
+import (
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+)
+
+// motionSteps is the number of intervals over which a Motion's bounds are found.
+const motionSteps = 16
+
+// Motion moves a Surface during each frame, from one transform at time 0 to another at time 1.
+// Rays are intersected with the Surface as it's placed at their Time,
+// so cameras that leave their shutters open while it moves blur it.
+// Its bounds enclose the whole movement, so acceleration structures find it at any time.
+// Moving lights aren't sampled; they're only found by paths that hit them.
+type Motion struct {
+	surf   render.Surface
+	path   *geom.Interpolation
+	bounds *geom.Bounds
+}
+
+// NewMotion returns a Surface that moves s from the transform from to the transform to.
+func NewMotion(s render.Surface, from, to *geom.Mtx) *Motion {
+	path := geom.NewInterpolation(from, to)
+	return &Motion{surf: s, path: path, bounds: sweep(s.Bounds(), path)}
+}
+
+// sweep returns the bounds around a local box as it moves from one transform to another.
+// Between steps, the corners of the box can curve away from the straight lines between them
+// by up to half of the distance they travel, so the bounds are padded by that much.
+func sweep(b *geom.Bounds, path *geom.Interpolation) *geom.Bounds {
+	if unbounded(b) {
+		return b
+	}
+	var corners []geom.Vec
+	for _, x := range []float64{b.Min.X, b.Max.X} {
+		for _, y := range []float64{b.Min.Y, b.Max.Y} {
+			for _, z := range []float64{b.Min.Z, b.Max.Z} {
+				corners = append(corners, geom.Vec{x, y, z})
+			}
+		}
+	}
+	prev := make([]geom.Vec, len(corners))
+	min, max := path.At(0).MultPoint(corners[0]), path.At(0).MultPoint(corners[0])
+	pad := 0.0
+	for k := 0; k <= motionSteps; k++ {
+		m := path.At(float64(k) / motionSteps)
+		for i, c := range corners {
+			pt := m.MultPoint(c)
+			min, max = min.Min(pt), max.Max(pt)
+			if d := pt.Minus(prev[i]).Len() / 2; k > 0 && d > pad {
+				pad = d
+			}
+			prev[i] = pt
+		}
+	}
+	margin := geom.Vec{pad, pad, pad}
+	return geom.NewBounds(min.Minus(margin), max.Plus(margin))
+}
+
+func (m *Motion) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	if ok, near, _ := m.bounds.Check(ray); !ok || near >= max {
+		return nil, 0
+	}
+	mtx := m.path.At(ray.Time)
+	local, scale := localRay(mtx, ray)
+	o, d := m.surf.Intersect(local, max*scale)
+	if o == nil {
+		return nil, 0
+	}
+	return &instanced{mtx: mtx, normals: mtx.Inverse().Transpose(), obj: o}, d / scale
+}
+
+func (m *Motion) Lights() []render.Object {
+	return nil
+}
+
+func (m *Motion) Bounds() *geom.Bounds {
+	return m.bounds
+}
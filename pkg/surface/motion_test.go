@@ -0,0 +1,45 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+func TestMotion(t *testing.T) {
+	from := geom.Shift(geom.Vec{-1, 0, 0})
+	to := geom.Shift(geom.Vec{1, 0.5, 0}).Mult(geom.Rotate(geom.Vec{0, 2, 0})).Mult(geom.Scale(geom.Vec{0.5, 1, 0.5}))
+	cube := surface.UnitCube()
+	motion := surface.NewMotion(cube, from, to)
+	bvh := surface.NewBVH(motion)
+	bounds := motion.Bounds()
+	rnd := rand.New(rand.NewSource(1))
+	hits := 0
+	for i, r := range rays(5000) {
+		r = geom.NewRayAt(r.Origin, r.Dir, rnd.Float64())
+		still := surface.NewInstance(cube, geom.Interpolate(from, to, r.Time))
+		o1, d1 := still.Intersect(r, math.Inf(1))
+		o2, d2 := bvh.Intersect(r, math.Inf(1))
+		if (o1 == nil) != (o2 == nil) || (o1 != nil && math.Abs(d1-d2) > 1e-9) {
+			t.Fatalf("ray %v at time %v: still hit %v at %v, moving hit %v at %v", i, r.Time, o1, d1, o2, d2)
+		}
+		if o2 == nil {
+			continue
+		}
+		hits++
+		if pt := r.Moved(d2); !bounds.Contains(pt) {
+			t.Errorf("ray %v at time %v hit %v, outside of bounds %v - %v", i, r.Time, pt, bounds.Min, bounds.Max)
+		}
+		n1, _ := o1.At(r.Moved(d1), r.Dir, rnd)
+		n2, _ := o2.At(r.Moved(d2), r.Dir, rnd)
+		if n1.Dot(n2) < 1-1e-9 {
+			t.Errorf("ray %v at time %v: still normal %v, moving normal %v", i, r.Time, n1, n2)
+		}
+	}
+	if hits < 100 {
+		t.Errorf("only %v rays hit", hits)
+	}
+}
@@ -0,0 +1,141 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+var infiniteBounds = geom.NewBounds(
+	geom.Vec{math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+	geom.Vec{math.Inf(1), math.Inf(1), math.Inf(1)},
+)
+
+// Plane describes an infinite plane.
+// Untransformed, it passes through the origin and faces up (+Y).
+// Its texture coordinates are its local X and Z coordinates, so textures repeat every unit.
+// Emissive planes glow where they're seen, but aren't sampled as lights since they have no finite area.
+type Plane struct {
+	flat
+	mat Material
+}
+
+// NewPlane returns a pointer to a new Plane Surface with an optional material.
+func NewPlane(m ...Material) *Plane {
+	p := &Plane{
+		flat: flat{mtx: geom.Identity()},
+		mat:  &DefaultMaterial{},
+	}
+	if len(m) > 0 {
+		p.mat = m[0]
+	}
+	return p.transform(geom.Identity())
+}
+
+func (p *Plane) transform(m *geom.Mtx) *Plane {
+	p.flat.transform(m)
+	return p
+}
+
+func (p *Plane) Shift(v geom.Vec) *Plane {
+	return p.transform(geom.Shift(v))
+}
+
+func (p *Plane) Scale(v geom.Vec) *Plane {
+	return p.transform(geom.Scale(v))
+}
+
+func (p *Plane) Rotate(v geom.Vec) *Plane {
+	return p.transform(geom.Rotate(v))
+}
+
+// Bounds are infinite, so BVHs, Trees, and BoundsAround treat planes separately.
+func (p *Plane) Bounds() *geom.Bounds {
+	return infiniteBounds
+}
+
+func (p *Plane) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	_, dist, ok := p.hit(ray, max)
+	if !ok {
+		return nil, 0
+	}
+	return p, dist
+}
+
+func (p *Plane) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	local := p.mtx.Inverse().MultPoint(pt)
+	n2, bsdf := p.mat.At(local.X, local.Z, in, p.normal, rnd)
+	return p.shading(n2), bsdf
+}
+
+func (p *Plane) Lights() []render.Object {
+	return nil
+}
+
+func (p *Plane) Light() rgb.Energy {
+	return p.mat.Light()
+}
+
+func (p *Plane) Transmit() rgb.Energy {
+	return p.mat.Transmit()
+}
+
+func (p *Plane) Medium() *render.Medium {
+	return p.mat.Medium()
+}
+
+// flat is the transform shared by planar surfaces, which lie in their local XZ plane, facing +Y.
+type flat struct {
+	mtx    *geom.Mtx
+	normal geom.Dir // in world space
+}
+
+func (f *flat) transform(m *geom.Mtx) {
+	f.mtx = f.mtx.Mult(m)
+	f.normal = f.mtx.Inverse().Transpose().MultDir(geom.Up)
+}
+
+// hit returns the local point where ray crosses the plane, and its distance,
+// and whether that distance is between bias and max.
+func (f *flat) hit(ray *geom.Ray, max float64) (local geom.Vec, dist float64, ok bool) {
+	r := f.mtx.Inverse().MultRay(ray)
+	if r.Dir.Y == 0 {
+		return local, 0, false
+	}
+	t := -r.Origin.Y / r.Dir.Y
+	if t <= 0 {
+		return local, 0, false
+	}
+	dist = f.mtx.MultDist(r.Dir.Scaled(t)).Len()
+	return r.Moved(t), dist, dist > bias && dist < max
+}
+
+// shading returns the world-space normal to shade with, given a material's tangent-space normal.
+// Texture coordinates increase along the local X and Z axes.
+func (f *flat) shading(local geom.Dir) geom.Dir {
+	return shading(f.normal, f.mtx.MultDist(geom.Vec{1, 0, 0}), f.mtx.MultDist(geom.Vec{0, 0, 1}), local)
+}
+
+// stretch returns the factor by which the transform scales area in the plane.
+func (f *flat) stretch() float64 {
+	x := f.mtx.MultDist(geom.Vec{1, 0, 0})
+	z := f.mtx.MultDist(geom.Vec{0, 0, 1})
+	return x.Cross(z).Len()
+}
+
+// corners returns the bounds around the transformed unit square centered on the origin.
+func (f *flat) corners() *geom.Bounds {
+	min := f.mtx.MultPoint(geom.Vec{-0.5, 0, -0.5})
+	max := min
+	for x := -0.5; x <= 0.5; x += 1 {
+		for z := -0.5; z <= 0.5; z += 1 {
+			pt := f.mtx.MultPoint(geom.Vec{x, 0, z})
+			min = min.Min(pt)
+			max = max.Max(pt)
+		}
+	}
+	return geom.NewBounds(min, max)
+}
@@ -0,0 +1,63 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+func TestFlatEmitters(t *testing.T) {
+	rotate, scale := geom.Vec{0.3, 0.2, 1.1}, geom.Vec{2, 1, 0.5}
+	tests := []struct {
+		name string
+		e    render.Emitter
+		s    render.Surface
+		area float64
+	}{
+		{"quad", surface.UnitQuad().Rotate(rotate).Scale(scale), surface.UnitQuad().Rotate(rotate).Scale(scale), 1},
+		{"disk", surface.UnitDisk().Rotate(rotate).Scale(scale), surface.UnitDisk().Rotate(rotate).Scale(scale), math.Pi / 4},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		if a := test.e.Area(); math.Abs(a-test.area) > 1e-9 {
+			t.Errorf("%v: area %v, want %v", test.name, a, test.area)
+		}
+		for i := 0; i < 1000; i++ {
+			pt, normal, pdf := test.e.SampleArea(rnd)
+			if math.Abs(pdf*test.area-1) > 1e-9 {
+				t.Fatalf("%v: pdf %v, want %v", test.name, pdf, 1/test.area)
+			}
+			// every sampled point should be on the surface, seen from either side
+			from := pt.Plus(normal.Scaled(math.Copysign(1, rnd.Float64()-0.5)))
+			dir, _ := pt.Minus(from).Unit()
+			o, dist := test.s.Intersect(geom.NewRay(from, dir), math.Inf(1))
+			if o == nil || math.Abs(dist-1) > 1e-9 {
+				t.Fatalf("%v: sampled point %v was hit at %v (object %v), want 1", test.name, pt, dist, o)
+			}
+		}
+	}
+}
+
+func TestPlane(t *testing.T) {
+	floor := surface.NewPlane().Shift(geom.Vec{0, -1, 0}).Rotate(geom.Vec{0.2, 0, 0})
+	ball := surface.UnitSphere().Shift(geom.Vec{0, 1, 0})
+	for _, s := range []render.Surface{surface.NewBVH(floor, ball), surface.NewTree(floor, ball), surface.NewList(floor, ball)} {
+		b := s.Bounds()
+		if b.Min != (geom.Vec{-0.5, 0.5, -0.5}) || b.Max != (geom.Vec{0.5, 1.5, 0.5}) {
+			t.Errorf("%T bounds are %v to %v, want the ball's", s, b.Min, b.Max)
+		}
+		down := geom.NewRay(geom.Vec{0, 2, 0}, geom.Dir{0, -1, 0})
+		if o, d := s.Intersect(down, math.Inf(1)); o != ball || math.Abs(d-0.5) > 1e-9 {
+			t.Errorf("%T: down ray hit %v at %v, want the ball at 0.5", s, o, d)
+		}
+		far := geom.NewRay(geom.Vec{100, 0, 100}, geom.Dir{0, -1, 0})
+		o, d := s.Intersect(far, math.Inf(1))
+		if o != floor || math.Abs(d-(1+100*math.Tan(0.2))) > 1e-9 {
+			t.Errorf("%T: distant ray hit %v at %v, want the floor at %v", s, o, d, 1+100*math.Tan(0.2))
+		}
+	}
+}
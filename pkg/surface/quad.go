@@ -0,0 +1,109 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// Quad describes a flat rectangle (or, once sheared, a parallelogram).
+// Untransformed, it's a 1x1 square centered on the origin in the XZ plane, facing up (+Y),
+// with texture coordinates from 0 to 1 along X and Z.
+type Quad struct {
+	flat
+	mat    Material
+	bounds *geom.Bounds
+}
+
+// UnitQuad returns a pointer to a new 1x1 Quad Surface with an optional material.
+func UnitQuad(m ...Material) *Quad {
+	q := &Quad{
+		flat: flat{mtx: geom.Identity()},
+		mat:  &DefaultMaterial{},
+	}
+	if len(m) > 0 {
+		q.mat = m[0]
+	}
+	return q.transform(geom.Identity())
+}
+
+func (q *Quad) transform(m *geom.Mtx) *Quad {
+	q.flat.transform(m)
+	q.bounds = q.corners()
+	return q
+}
+
+func (q *Quad) Shift(v geom.Vec) *Quad {
+	return q.transform(geom.Shift(v))
+}
+
+func (q *Quad) Scale(v geom.Vec) *Quad {
+	return q.transform(geom.Scale(v))
+}
+
+func (q *Quad) Rotate(v geom.Vec) *Quad {
+	return q.transform(geom.Rotate(v))
+}
+
+func (q *Quad) Center() geom.Vec {
+	return q.mtx.MultPoint(geom.Vec{})
+}
+
+func (q *Quad) Bounds() *geom.Bounds {
+	return q.bounds
+}
+
+func (q *Quad) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	if ok, near, _ := q.bounds.Check(ray); !ok || near >= max {
+		return nil, 0
+	}
+	local, dist, ok := q.hit(ray, max)
+	if !ok || math.Abs(local.X) > 0.5 || math.Abs(local.Z) > 0.5 {
+		return nil, 0
+	}
+	return q, dist
+}
+
+func (q *Quad) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	local := q.mtx.Inverse().MultPoint(pt)
+	n2, bsdf := q.mat.At(local.X+0.5, local.Z+0.5, in, q.normal, rnd)
+	return q.shading(n2), bsdf
+}
+
+// Area returns the surface area of the transformed quad.
+func (q *Quad) Area() float64 {
+	return q.stretch()
+}
+
+// SampleArea returns a uniformly distributed point on the quad, its normal, and its density by area.
+func (q *Quad) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	pt = q.mtx.MultPoint(geom.Vec{rnd.Float64() - 0.5, 0, rnd.Float64() - 0.5})
+	return pt, q.normal, 1 / q.Area()
+}
+
+// AreaPDF returns the normal at pt and the density with which SampleArea chooses it.
+func (q *Quad) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	return q.normal, 1 / q.Area()
+}
+
+func (q *Quad) Lights() []render.Object {
+	if !q.mat.Light().Zero() {
+		return []render.Object{q}
+	}
+	return nil
+}
+
+func (q *Quad) Light() rgb.Energy {
+	return q.mat.Light()
+}
+
+func (q *Quad) Transmit() rgb.Energy {
+	return q.mat.Transmit()
+}
+
+func (q *Quad) Medium() *render.Medium {
+	return q.mat.Medium()
+}
@@ -0,0 +1,121 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+// leaning is a material whose tangent-space normal leans towards increasing u or v.
+// It records the texture coordinates it was last asked about.
+type leaning struct {
+	normal geom.Dir
+	u, v   float64
+}
+
+func (l *leaning) At(u, v float64, in, norm geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
+	l.u, l.v = u, v
+	return l.normal, surface.Lambert{}
+}
+
+func (l *leaning) Light() rgb.Energy {
+	return rgb.Black
+}
+
+func (l *leaning) Transmit() rgb.Energy {
+	return rgb.Black
+}
+
+func (l *leaning) Medium() *render.Medium {
+	return nil
+}
+
+// TestShading checks that normals from materials lean the way texture coordinates increase.
+func TestShading(t *testing.T) {
+	const angle = 0.3
+	towardsU := geom.Dir{math.Sin(angle), math.Cos(angle), 0}
+	towardsV := geom.Dir{0, math.Cos(angle), math.Sin(angle)}
+	grid := func(mat surface.Material) render.Surface {
+		var verts []surface.Vertex
+		var faces []surface.Face
+		const n = 8
+		for i := 0; i <= n; i++ {
+			for j := 0; j <= n; j++ {
+				u, v := float64(i)/n, float64(j)/n
+				p := geom.Vec{u - 0.5, 0.2 * math.Sin(3*u), v - 0.5} // bends about the Z axis
+				norm, _ := geom.Vec{-0.6 * math.Cos(3*u), 1, 0}.Unit()
+				verts = append(verts, surface.Vertex{Point: p, Normal: norm, Texture: geom.Vec{u, v, 0}})
+			}
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a, b, c, d := i*(n+1)+j, (i+1)*(n+1)+j, (i+1)*(n+1)+j+1, i*(n+1)+j+1
+				faces = append(faces, surface.Face{Verts: [3]int{a, c, b}}, surface.Face{Verts: [3]int{a, d, c}})
+			}
+		}
+		return surface.NewMesh(verts, faces, []surface.Material{mat}, false)
+	}
+	tests := []struct {
+		name string
+		surf func(m surface.Material) render.Surface
+	}{
+		{"sphere", func(m surface.Material) render.Surface { return surface.UnitSphere(m).Rotate(geom.Vec{0.3, 0, 0.2}) }},
+		{"cube", func(m surface.Material) render.Surface { return surface.UnitCube(m).Rotate(geom.Vec{0.3, 0.4, 0}) }},
+		{"quad", func(m surface.Material) render.Surface { return surface.UnitQuad(m).Rotate(geom.Vec{-1, 0, 0.2}) }},
+		{"cylinder", func(m surface.Material) render.Surface { return surface.UnitCylinder(m).Rotate(geom.Vec{0.4, 0, 0}) }},
+		{"torus", func(m surface.Material) render.Surface { return surface.UnitTorus(0.15, m).Rotate(geom.Vec{-1, 0, 0}) }},
+		{"mesh", func(m surface.Material) render.Surface {
+			return surface.NewInstance(grid(m)).Rotate(geom.Vec{-1, 0, 0})
+		}},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	const eps = 1e-5
+	for _, test := range tests {
+		for _, lean := range []struct {
+			normal geom.Dir
+			coord  func(m *leaning) float64
+		}{
+			{towardsU, func(m *leaning) float64 { return m.u }},
+			{towardsV, func(m *leaning) float64 { return m.v }},
+		} {
+			flat, mat := &leaning{normal: geom.Up}, &leaning{normal: lean.normal}
+			plain, bumpy := test.surf(flat), test.surf(mat)
+			checked := 0
+			for i := 0; i < 200; i++ {
+				from := geom.Vec(geom.RandDirection(rnd)).Scaled(3)
+				dir, _ := geom.Vec(geom.RandDirection(rnd)).Scaled(0.3).Minus(from).Unit()
+				r := geom.NewRay(from, dir)
+				o1, d1 := plain.Intersect(r, math.Inf(1))
+				o2, _ := bumpy.Intersect(r, math.Inf(1))
+				if o1 == nil || o2 == nil {
+					continue
+				}
+				pt := r.Moved(d1)
+				n, _ := o1.At(pt, dir, rnd)
+				shaded, _ := o2.At(pt, dir, rnd)
+				if got := math.Acos(math.Min(1, n.Dot(shaded))); math.Abs(got-angle) > 1e-6 {
+					t.Fatalf("%v: normal leans %v, expected %v", test.name, got, angle)
+				}
+				before := lean.coord(mat)
+				towards, _ := shaded.Scaled(1).Minus(n.Scaled(n.Dot(shaded))).Unit()
+				o1.At(pt.Plus(towards.Scaled(eps)), dir, rnd)
+				after := lean.coord(flat)
+				if math.Abs(after-before) > 0.5 {
+					continue // across a seam
+				}
+				if after <= before {
+					t.Fatalf("%v: normal at %v leans towards %v, where the coordinate goes from %v to %v", test.name, pt, towards, before, after)
+				}
+				checked++
+			}
+			if checked < 20 {
+				t.Errorf("%v: only checked %v points", test.name, checked)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package surface
+
+import (
+	"math"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/rgb"
+)
+
+// shape holds the transform and material shared by the quadric and quartic surfaces
+// (cylinders, cones, tori, and capsules), which are defined in a local space
+// within the box from lo to hi.
+type shape struct {
+	mtx     *geom.Mtx
+	normals *geom.Mtx // transforms local normals to world space
+	mat     Material
+	bounds  *geom.Bounds
+	lo, hi  geom.Vec
+}
+
+func newShape(lo, hi geom.Vec, m []Material) shape {
+	s := shape{
+		mtx: geom.Identity(),
+		mat: &DefaultMaterial{},
+		lo:  lo,
+		hi:  hi,
+	}
+	if len(m) > 0 {
+		s.mat = m[0]
+	}
+	s.transform(geom.Identity())
+	return s
+}
+
+func (s *shape) transform(m *geom.Mtx) {
+	s.mtx = s.mtx.Mult(m)
+	s.normals = s.mtx.Inverse().Transpose()
+	min := s.mtx.MultPoint(s.lo)
+	max := min
+	for _, x := range []float64{s.lo.X, s.hi.X} {
+		for _, y := range []float64{s.lo.Y, s.hi.Y} {
+			for _, z := range []float64{s.lo.Z, s.hi.Z} {
+				pt := s.mtx.MultPoint(geom.Vec{x, y, z})
+				min = min.Min(pt)
+				max = max.Max(pt)
+			}
+		}
+	}
+	s.bounds = geom.NewBounds(min, max)
+}
+
+// local returns ray in local space, or nil if it misses the bounds before max.
+func (s *shape) local(ray *geom.Ray, max float64) *geom.Ray {
+	if ok, near, _ := s.bounds.Check(ray); !ok || near >= max {
+		return nil
+	}
+	return s.mtx.Inverse().MultRay(ray)
+}
+
+// nearest returns the world distance to the nearest of the local distances ts along r
+// that is between bias and max.
+func (s *shape) nearest(r *geom.Ray, ts []float64, max float64) (dist float64, ok bool) {
+	dist = max
+	for _, t := range ts {
+		if t <= 0 {
+			continue
+		}
+		if d := s.mtx.MultDist(r.Dir.Scaled(t)).Len(); d > bias && d < dist {
+			dist, ok = d, true
+		}
+	}
+	return dist, ok
+}
+
+// point returns pt in local space.
+func (s *shape) point(pt geom.Vec) geom.Vec {
+	return s.mtx.Inverse().MultPoint(pt)
+}
+
+// shading returns the world-space normal to shade with, given a material's tangent-space normal
+// and the local directions in which texture coordinates increase.
+func (s *shape) shading(normal geom.Dir, dpdu, dpdv geom.Vec, local geom.Dir) geom.Dir {
+	return shading(normal, s.mtx.MultDist(dpdu), s.mtx.MultDist(dpdv), local)
+}
+
+// normal transforms a local normal into world space.
+func (s *shape) normal(n geom.Vec) geom.Dir {
+	dir, ok := s.normals.MultDist(n).Unit()
+	if !ok {
+		return s.normals.MultDir(geom.Up)
+	}
+	return dir
+}
+
+func (s *shape) Bounds() *geom.Bounds {
+	return s.bounds
+}
+
+func (s *shape) Center() geom.Vec {
+	return s.mtx.MultPoint(geom.Vec{})
+}
+
+func (s *shape) Light() rgb.Energy {
+	return s.mat.Light()
+}
+
+func (s *shape) Transmit() rgb.Energy {
+	return s.mat.Transmit()
+}
+
+func (s *shape) Medium() *render.Medium {
+	return s.mat.Medium()
+}
+
+// angle returns the angle of (x, z) around the Y axis, from 0 to 1.
+func angle(x, z float64) float64 {
+	a := math.Atan2(z, x) / (2 * math.Pi)
+	if a < 0 {
+		a++
+	}
+	return a
+}
+
+// quadratic appends the real roots of a*t*t + b*t + c to ts.
+func quadratic(ts []float64, a, b, c float64) []float64 {
+	if a == 0 {
+		if b == 0 {
+			return ts
+		}
+		return append(ts, -c/b)
+	}
+	det := b*b - 4*a*c
+	if det < 0 {
+		return ts
+	}
+	// https://en.wikipedia.org/wiki/Loss_of_significance#A_better_algorithm
+	q := -0.5 * (b + math.Copysign(math.Sqrt(det), b))
+	if q == 0 {
+		return append(ts, 0)
+	}
+	return append(ts, q/a, c/q)
+}
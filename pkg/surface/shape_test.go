@@ -0,0 +1,123 @@
+package surface_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+	"github.com/hunterloftis/pbr2/pkg/surface"
+)
+
+func radial(p geom.Vec) float64 {
+	return math.Sqrt(p.X*p.X + p.Z*p.Z)
+}
+
+// shapes lists each untransformed shape with a function that's zero on its surface
+// and, unless it's near the origin, a function that returns a point inside it.
+var shapes = []struct {
+	name   string
+	surf   func() render.Surface
+	zero   func(p geom.Vec) float64
+	inside func(rnd *rand.Rand) geom.Vec
+	open   bool // can be seen into and through
+}{
+	{
+		name: "cylinder",
+		surf: func() render.Surface { return surface.UnitCylinder() },
+		zero: func(p geom.Vec) float64 { return math.Max(radial(p)-0.5, math.Abs(p.Y)-0.5) },
+	},
+	{
+		name: "open cylinder",
+		surf: func() render.Surface { return surface.OpenCylinder() },
+		zero: func(p geom.Vec) float64 { return radial(p) - 0.5 },
+		open: true,
+	},
+	{
+		name: "cone",
+		surf: func() render.Surface { return surface.UnitCone() },
+		zero: func(p geom.Vec) float64 { return math.Max((radial(p)-0.5*(0.5-p.Y))/math.Sqrt(1.25), -0.5-p.Y) },
+	},
+	{
+		name: "torus",
+		surf: func() render.Surface { return surface.UnitTorus(0.1) },
+		zero: func(p geom.Vec) float64 { return math.Hypot(radial(p)-0.4, p.Y) - 0.1 },
+		inside: func(rnd *rand.Rand) geom.Vec {
+			a := rnd.Float64() * 2 * math.Pi
+			return geom.Vec{0.4 * math.Cos(a), 0, 0.4 * math.Sin(a)}
+		},
+	},
+	{
+		name: "capsule",
+		surf: func() render.Surface { return surface.UnitCapsule(0.2) },
+		zero: func(p geom.Vec) float64 {
+			return p.Minus(geom.Vec{0, math.Max(-0.3, math.Min(0.3, p.Y)), 0}).Len() - 0.2
+		},
+	},
+}
+
+func TestShapes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, shape := range shapes {
+		s := shape.surf()
+		for i := 0; i < 2000; i++ {
+			target := geom.Vec{0, rnd.Float64() - 0.5, 0}.Scaled(0.5)
+			if shape.inside != nil {
+				target = shape.inside(rnd)
+			}
+			from := geom.Vec(geom.RandDirection(rnd)).Scaled(3)
+			dir, _ := target.Minus(from).Unit()
+			ray := geom.NewRay(from, dir)
+			o, dist := s.Intersect(ray, math.Inf(1))
+			if o == nil {
+				if shape.open {
+					continue
+				}
+				t.Fatalf("%v: ray %v from %v towards %v missed", shape.name, i, from, target)
+			}
+			if dist > target.Minus(from).Len() && !shape.open {
+				t.Fatalf("%v: ray %v from %v hit at %v, beyond %v", shape.name, i, from, dist, target)
+			}
+			pt := ray.Moved(dist)
+			if z := shape.zero(pt); math.Abs(z) > 1e-9 {
+				t.Fatalf("%v: ray %v hit %v, which is %v from the surface", shape.name, i, pt, z)
+			}
+			normal, _ := o.At(pt, dir, rnd)
+			if normal.Dot(dir) > 0 && !shape.open {
+				t.Fatalf("%v: ray %v hit %v with an inward normal %v", shape.name, i, pt, normal)
+			}
+		}
+	}
+}
+
+func TestShapesTransformed(t *testing.T) {
+	shift, rotate, scale := geom.Vec{0.1, 0.2, -0.3}, geom.Vec{0.5, 1, 0.2}, geom.Vec{1.5, 0.5, 1}
+	m := geom.Shift(shift).Mult(geom.Rotate(rotate)).Mult(geom.Scale(scale))
+	transformed := []render.Surface{
+		surface.UnitCylinder().Shift(shift).Rotate(rotate).Scale(scale),
+		surface.OpenCylinder().Shift(shift).Rotate(rotate).Scale(scale),
+		surface.UnitCone().Shift(shift).Rotate(rotate).Scale(scale),
+		surface.UnitTorus(0.1).Shift(shift).Rotate(rotate).Scale(scale),
+		surface.UnitCapsule(0.2).Shift(shift).Rotate(rotate).Scale(scale),
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i, s := range transformed {
+		inst := surface.NewInstance(shapes[i].surf(), m)
+		for j, r := range rays(1000) {
+			o1, d1 := s.Intersect(r, math.Inf(1))
+			o2, d2 := inst.Intersect(r, math.Inf(1))
+			if (o1 == nil) != (o2 == nil) || math.Abs(d1-d2) > 1e-9 {
+				t.Fatalf("%v: ray %v hit at %v, instance hit at %v", shapes[i].name, j, d1, d2)
+			}
+			if o1 == nil {
+				continue
+			}
+			n1, _ := o1.At(r.Moved(d1), r.Dir, rnd)
+			n2, _ := o2.At(r.Moved(d2), r.Dir, rnd)
+			if n1.Dot(n2) < 1-1e-9 {
+				t.Fatalf("%v: ray %v normal %v, instance normal %v", shapes[i].name, j, n1, n2)
+			}
+		}
+	}
+}
@@ -43,6 +43,7 @@ func (s *Sphere) transform(t *geom.Mtx) *Sphere {
 		}
 	}
 	s.bounds = geom.NewBounds(min, max)
+	s.mtx.Prepare()
 	return s
 }
 
@@ -85,14 +86,14 @@ func (s *Sphere) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist
 	t1 := b - root
 	if t1 > 0 {
 		dist := s.mtx.MultDist(r.Dir.Scaled(t1)).Len()
-		if dist > bias {
+		if dist > bias && dist < max {
 			return s, dist
 		}
 	}
 	t2 := b + root
 	if t2 > 0 {
 		dist := s.mtx.MultDist(r.Dir.Scaled(t2)).Len()
-		if dist > bias {
+		if dist > bias && dist < max {
 			return s, dist
 		}
 	}
@@ -100,15 +101,16 @@ func (s *Sphere) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist
 }
 
 // At returns the surface normal given a point on the surface.
+// Texture coordinates wrap around the sphere in u, and go from its bottom to its top in v.
 func (s *Sphere) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
 	i := s.mtx.Inverse()
 	p := i.MultPoint(pt)
 	pu, _ := p.Unit()
 	n := s.mtx.MultDir(pu)
-	n2, bsdf := s.mat.At(0, 0, in, n, rnd)
-	_ = n2
-	normal = n // TODO: compute normal by combining n and n2 (and a bitangent)
-	return normal, bsdf
+	u, v := angle(pu.X, pu.Z), math.Asin(math.Max(-1, math.Min(1, pu.Y)))/math.Pi+0.5
+	n2, bsdf := s.mat.At(u, v, in, n, rnd)
+	dpdu := s.mtx.MultDist(geom.Vec{-pu.Z, 0, pu.X})
+	return shading(n, dpdu, s.mtx.MultDist(geom.Vec{0, 1, 0}), n2), bsdf
 }
 
 func (s *Sphere) Light() rgb.Energy {
@@ -119,6 +121,44 @@ func (s *Sphere) Transmit() rgb.Energy {
 	return s.mat.Transmit()
 }
 
+func (s *Sphere) Medium() *render.Medium {
+	return s.mat.Medium()
+}
+
+// Area approximates the surface area of the transformed sphere (an ellipsoid).
+// https://en.wikipedia.org/wiki/Ellipsoid#Approximate_formula
+func (s *Sphere) Area() float64 {
+	const p = 1.6075
+	a := math.Pow(s.mtx.MultDist(geom.Vec{0.5, 0, 0}).Len(), p)
+	b := math.Pow(s.mtx.MultDist(geom.Vec{0, 0.5, 0}).Len(), p)
+	c := math.Pow(s.mtx.MultDist(geom.Vec{0, 0, 0.5}).Len(), p)
+	return 4 * math.Pi * math.Pow((a*b+a*c+b*c)/3, 1/p)
+}
+
+// SampleArea returns a point on the sphere, its normal, and its density by area.
+// Points are uniformly distributed over the untransformed sphere,
+// so they are only uniform by area when the sphere is scaled equally along each axis.
+func (s *Sphere) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	pt = s.mtx.MultPoint(geom.RandDirection(rnd).Scaled(0.5))
+	normal, pdf = s.AreaPDF(pt)
+	return pt, normal, pdf
+}
+
+// AreaPDF returns the normal at pt and the density with which SampleArea chooses it.
+// The local density, 1 / π, is divided by the factor by which the transform stretches area at pt.
+// https://en.wikipedia.org/wiki/Normal_(geometry)#Transforming_normals
+func (s *Sphere) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	i := s.mtx.Inverse()
+	local, _ := i.MultPoint(pt).Unit()
+	n := i.Transpose().MultDist(geom.Vec(local))
+	x := s.mtx.MultDist(geom.Vec{1, 0, 0})
+	y := s.mtx.MultDist(geom.Vec{0, 1, 0})
+	z := s.mtx.MultDist(geom.Vec{0, 0, 1})
+	stretch := math.Abs(x.Dot(y.Cross(z))) * n.Len()
+	normal, _ = n.Unit()
+	return normal, 1 / (math.Pi * stretch)
+}
+
 func (s *Sphere) Lights() []render.Object {
 	if !s.mat.Light().Zero() {
 		return []render.Object{s}
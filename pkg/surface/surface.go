@@ -1,6 +1,8 @@
 package surface
 
 import (
+	"math"
+
 	"github.com/hunterloftis/pbr2/pkg/geom"
 	"github.com/hunterloftis/pbr2/pkg/render"
 )
@@ -9,13 +11,55 @@ import (
 // Applying bias provides more robust processing of geometry.
 const bias = 1e-12
 
+// BoundsAround returns the bounds around every surface in ss with finite bounds.
+// Unbounded surfaces, like Planes, are left out so that they don't make the result infinite.
 func BoundsAround(ss []render.Surface) *geom.Bounds {
-	if len(ss) == 0 {
+	var bounds *geom.Bounds
+	for _, s := range ss {
+		if unbounded(s.Bounds()) {
+			continue
+		}
+		if bounds == nil {
+			bounds = s.Bounds()
+		}
+		bounds = geom.MergeBounds(bounds, s.Bounds())
+	}
+	if bounds == nil {
 		return geom.NewBounds(geom.Vec{}, geom.Vec{})
 	}
-	Bounds := ss[0].Bounds()
+	return bounds
+}
+
+// unbounded returns whether b extends infinitely along some axis.
+func unbounded(b *geom.Bounds) bool {
+	for a := 0; a < 3; a++ {
+		if math.IsInf(b.MinArray[a], 0) || math.IsInf(b.MaxArray[a], 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// partition separates surfaces with finite bounds from unbounded ones,
+// which acceleration structures test separately.
+func partition(ss []render.Surface) (finite, infinite []render.Surface) {
+	for _, s := range ss {
+		if unbounded(s.Bounds()) {
+			infinite = append(infinite, s)
+		} else {
+			finite = append(finite, s)
+		}
+	}
+	return finite, infinite
+}
+
+// intersectAll tests each surface in ss, returning the nearest hit before max.
+func intersectAll(ss []render.Surface, r *geom.Ray, max float64) (obj render.Object, dist float64) {
+	dist = max
 	for _, s := range ss {
-		Bounds = geom.MergeBounds(Bounds, s.Bounds())
+		if o, d := s.Intersect(r, dist); o != nil {
+			obj, dist = o, d
+		}
 	}
-	return Bounds
+	return obj, dist
 }
@@ -0,0 +1,187 @@
+package surface
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hunterloftis/pbr2/pkg/geom"
+	"github.com/hunterloftis/pbr2/pkg/render"
+)
+
+// Torus describes a ring around the Y axis.
+// Untransformed, it's 1 unit across, and its tube has the given minor radius.
+// Texture coordinates wrap around the ring (u) and around the tube (v).
+type Torus struct {
+	shape
+	major, minor float64
+}
+
+// UnitTorus returns a pointer to a new Torus Surface, whose tube has a radius of minor (up to 0.25),
+// with an optional material.
+func UnitTorus(minor float64, m ...Material) *Torus {
+	minor = math.Max(0, math.Min(0.25, minor))
+	return &Torus{
+		shape: newShape(geom.Vec{-0.5, -minor, -0.5}, geom.Vec{0.5, minor, 0.5}, m),
+		major: 0.5 - minor,
+		minor: minor,
+	}
+}
+
+func (t *Torus) transform(m *geom.Mtx) *Torus {
+	t.shape.transform(m)
+	return t
+}
+
+func (t *Torus) Shift(v geom.Vec) *Torus {
+	return t.transform(geom.Shift(v))
+}
+
+func (t *Torus) Scale(v geom.Vec) *Torus {
+	return t.transform(geom.Scale(v))
+}
+
+func (t *Torus) Rotate(v geom.Vec) *Torus {
+	return t.transform(geom.Rotate(v))
+}
+
+// Intersect solves the quartic (|p|² + R² - r²)² = 4R²(x² + z²) along the ray.
+// The ray first starts where it enters the local bounds, which keeps the quartic well-conditioned
+// for distant rays.
+// http://www.cosinekitty.com/raytrace/chapter13_torus.html
+func (t *Torus) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	r := t.local(ray, max)
+	if r == nil {
+		return nil, 0
+	}
+	lb := geom.NewBounds(t.lo, t.hi)
+	ok, start, _ := lb.Check(r)
+	if !ok {
+		return nil, 0
+	}
+	start = math.Max(0, start-t.minor) // stay clear of the surface so that no root is lost
+	o, d := r.Moved(start), r.Dir
+	R2, r2 := t.major*t.major, t.minor*t.minor
+	f := o.Dot(geom.Vec(d))
+	e := o.Dot(o) + R2 - r2
+	var ts [4]float64
+	roots := quartic(ts[:0],
+		4*f,
+		4*f*f+2*e-4*R2*(d.X*d.X+d.Z*d.Z),
+		4*f*e-8*R2*(o.X*d.X+o.Z*d.Z),
+		e*e-4*R2*(o.X*o.X+o.Z*o.Z),
+	)
+	for i := range roots {
+		roots[i] += start
+	}
+	if dist, ok := t.nearest(r, roots, max); ok {
+		return t, dist
+	}
+	return nil, 0
+}
+
+func (t *Torus) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (normal geom.Dir, bsdf render.BSDF) {
+	p := t.point(pt)
+	radial := math.Sqrt(p.X*p.X + p.Z*p.Z)
+	center := geom.Vec{p.X, 0, p.Z}
+	if radial > 0 {
+		center = center.Scaled(t.major / radial)
+	}
+	tube := p.Minus(center)
+	normal = t.normal(tube)
+	u := angle(p.X, p.Z)
+	v := math.Atan2(p.Y, radial-t.major)/(2*math.Pi) + 0.5
+	n2, bsdf := t.mat.At(u, v, in, normal, rnd)
+	out := geom.Vec{p.X, 0, p.Z} // away from the axis, around which v turns
+	if radial > 0 {
+		out = out.Scaled(1 / radial)
+	}
+	dpdv := out.Scaled(-tube.Y).Plus(geom.Vec{0, radial - t.major, 0})
+	return t.shading(normal, geom.Vec{-p.Z, 0, p.X}, dpdv, n2), bsdf
+}
+
+func (t *Torus) Lights() []render.Object {
+	if !t.mat.Light().Zero() {
+		return []render.Object{t}
+	}
+	return nil
+}
+
+// quartic appends the real roots of x⁴ + a·x³ + b·x² + c·x + d to xs,
+// each refined with a few steps of Newton's method.
+// https://en.wikipedia.org/wiki/Quartic_function#Ferrari's_solution
+// https://github.com/erich666/GraphicsGems/blob/master/gems/Roots3And4.c
+func quartic(xs []float64, a, b, c, d float64) []float64 {
+	// substitute x = y - a/4 to eliminate the cubic term: y⁴ + p·y² + q·y + r
+	sub := a / 4
+	sq := sub * sub
+	p := b - 6*sq
+	q := c - 2*b*sub + 8*sq*sub
+	r := d - c*sub + b*sq - 3*sq*sq
+	n := len(xs)
+	if math.Abs(r) < 1e-14 {
+		// y·(y³ + p·y + q) = 0
+		xs = append(xs, 0)
+		xs = cubic(xs, 0, p, q)
+	} else {
+		// solve the resolvent cubic, and use one of its roots to factor the quartic into two quadratics
+		var zs [3]float64
+		roots := cubic(zs[:0], -p/2, -r, r*p/2-q*q/8)
+		if len(roots) == 0 {
+			return xs
+		}
+		z := roots[0] // the largest root keeps 2z - p positive
+		for _, root := range roots[1:] {
+			z = math.Max(z, root)
+		}
+		u := z*z - r
+		v := 2*z - p
+		if u < -1e-12 || v < -1e-12 {
+			return xs
+		}
+		u = math.Sqrt(math.Max(0, u))
+		v = math.Sqrt(math.Max(0, v))
+		if q < 0 {
+			v = -v
+		}
+		xs = quadratic(xs, 1, v, z-u)
+		xs = quadratic(xs, 1, -v, z+u)
+	}
+	for i := n; i < len(xs); i++ {
+		y := xs[i]
+		for k := 0; k < 3; k++ {
+			f := (((y*y+p)*y)+q)*y + r
+			df := (4*y*y+2*p)*y + q
+			if df == 0 {
+				break
+			}
+			y -= f / df
+		}
+		xs[i] = y - sub
+	}
+	return xs
+}
+
+// cubic appends the real roots of x³ + a·x² + b·x + c to xs.
+// https://en.wikipedia.org/wiki/Cubic_equation#Trigonometric_and_hyperbolic_solutions
+func cubic(xs []float64, a, b, c float64) []float64 {
+	sub := a / 3
+	p := b/3 - sub*sub
+	q := sub*sub*sub - sub*b/2 + c/2
+	p3 := p * p * p
+	det := q*q + p3
+	switch {
+	case math.Abs(det) < 1e-18:
+		if math.Abs(q) < 1e-18 {
+			return append(xs, -sub)
+		}
+		u := math.Cbrt(-q)
+		return append(xs, 2*u-sub, -u-sub)
+	case det < 0:
+		phi := math.Acos(-q/math.Sqrt(-p3)) / 3
+		t := 2 * math.Sqrt(-p)
+		return append(xs, t*math.Cos(phi)-sub, -t*math.Cos(phi+math.Pi/3)-sub, -t*math.Cos(phi-math.Pi/3)-sub)
+	default:
+		s := math.Sqrt(det)
+		return append(xs, math.Cbrt(s-q)-math.Cbrt(s+q)-sub)
+	}
+}
@@ -12,9 +12,11 @@ const (
 	maxDepth    = 16
 )
 
+// Tree is a k-d tree. Unbounded surfaces, like Planes, are kept outside of it and tested separately.
 type Tree struct {
 	branch
-	lights []render.Object
+	infinite []render.Surface
+	lights   []render.Object
 }
 
 type branch struct {
@@ -28,15 +30,28 @@ type branch struct {
 }
 
 func NewTree(ss ...render.Surface) *Tree {
+	finite, infinite := partition(ss)
 	t := Tree{
-		branch: *newBranch(BoundsAround(ss), ss, maxDepth),
+		branch:   *newBranch(BoundsAround(finite), finite, maxDepth),
+		infinite: infinite,
 	}
-	for _, s := range t.branch.surfaces {
+	for _, s := range ss {
 		t.lights = append(t.lights, s.Lights()...)
 	}
 	return &t
 }
 
+func (t *Tree) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
+	obj, dist = t.branch.Intersect(ray, max)
+	if obj == nil {
+		dist = max
+	}
+	if o, d := intersectAll(t.infinite, ray, dist); o != nil {
+		obj, dist = o, d
+	}
+	return obj, dist
+}
+
 func (t *Tree) Lights() []render.Object {
 	return t.lights
 }
@@ -1,6 +1,7 @@
 package surface
 
 import (
+	"math"
 	"math/rand"
 
 	"github.com/hunterloftis/pbr2/pkg/geom"
@@ -61,6 +62,27 @@ func (t *Triangle) Bounds() *geom.Bounds {
 	return t.bounds
 }
 
+// Area returns the surface area of the triangle.
+func (t *Triangle) Area() float64 {
+	return t.edge1.Cross(t.edge2).Len() * 0.5
+}
+
+// SampleArea returns a uniformly distributed point on the triangle, its geometric normal, and its density by area.
+// http://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations.html#SamplingaTriangle
+func (t *Triangle) SampleArea(rnd *rand.Rand) (pt geom.Vec, normal geom.Dir, pdf float64) {
+	su := math.Sqrt(rnd.Float64())
+	u, v := 1-su, rnd.Float64()*su
+	pt = t.Points[0].Plus(t.edge1.Scaled(u)).Plus(t.edge2.Scaled(v))
+	normal, pdf = t.AreaPDF(pt)
+	return pt, normal, pdf
+}
+
+// AreaPDF returns the geometric normal at pt and the density with which SampleArea chooses it.
+func (t *Triangle) AreaPDF(pt geom.Vec) (normal geom.Dir, pdf float64) {
+	normal, _ = t.edge1.Cross(t.edge2).Unit()
+	return normal, 1 / t.Area()
+}
+
 // https://en.wikipedia.org/wiki/M%C3%B6ller%E2%80%93Trumbore_intersection_algorithm
 func (t *Triangle) Intersect(ray *geom.Ray, max float64) (obj render.Object, dist float64) {
 	if ok, near, _ := t.bounds.Check(ray); !ok || near >= max {
@@ -90,16 +112,33 @@ func (t *Triangle) Intersect(ray *geom.Ray, max float64) (obj render.Object, dis
 }
 
 // At returns the material at a point on the Triangle
+// Its tangent is the same at each vertex, since a lone Triangle doesn't know its neighbors;
+// Meshes share tangents between the faces around each vertex.
 // https://stackoverflow.com/questions/21210774/normal-mapping-on-procedural-sphere
 func (t *Triangle) At(pt geom.Vec, in geom.Dir, rnd *rand.Rand) (geom.Dir, render.BSDF) {
 	u, v, w := t.Bary(pt)
 	n := t.normal(u, v, w)
 	texture := t.texture(u, v, w)
 	n2, bsdf := t.Mat.At(texture.X, texture.Y, in, n, rnd)
-	// TODO: compute binormal and combine texture normal with n to return actual normal
-	_ = n2
-	normal := n
-	return normal, bsdf
+	if n2 != geom.Up {
+		dpdu, dpdv := derivatives(t.edge1, t.edge2, t.Texture[1].Minus(t.Texture[0]), t.Texture[2].Minus(t.Texture[0]))
+		n = shading(n, dpdu, dpdv, n2)
+	}
+	return n, bsdf
+}
+
+// derivatives returns the rates at which points change with texture coordinates u and v across a triangle,
+// given its edges and the change in texture coordinates along each.
+// They're zero if the texture coordinates don't change across the triangle.
+// http://www.pbr-book.org/3ed-2018/Shapes/Triangle_Meshes.html
+func derivatives(edge1, edge2, duv1, duv2 geom.Vec) (dpdu, dpdv geom.Vec) {
+	det := duv1.X*duv2.Y - duv2.X*duv1.Y
+	if det == 0 {
+		return geom.Vec{}, geom.Vec{}
+	}
+	dpdu = edge1.Scaled(duv2.Y).Minus(edge2.Scaled(duv1.Y)).Scaled(1 / det)
+	dpdv = edge2.Scaled(duv1.X).Minus(edge1.Scaled(duv2.X)).Scaled(1 / det)
+	return dpdu, dpdv
 }
 
 func (t *Triangle) Lights() []render.Object {
@@ -117,6 +156,10 @@ func (t *Triangle) Transmit() rgb.Energy {
 	return t.Mat.Transmit()
 }
 
+func (t *Triangle) Medium() *render.Medium {
+	return t.Mat.Medium()
+}
+
 // SetNormals sets values for each vertex normal
 func (t *Triangle) SetNormals(a, b, c geom.Dir) {
 	t.Normals[0] = a